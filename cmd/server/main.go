@@ -4,7 +4,11 @@ import (
 	"flag"
 	"fmt"
 	"log"
+	"math"
 	"os"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/ayushvyas-1/gcache/internal/cache"
 )
@@ -14,7 +18,12 @@ func main() {
 	var (
 		mode        = flag.String("mode", "server", "Mode: 'server' or 'client'")
 		address     = flag.String("addr", "localhost:8080", "Server address")
-		capacity    = flag.Int("capacity", 1000, "Cache capacity (server mode only)")
+		capacity    = flag.Int("capacity", 1000, "Cache capacity in keys, 0 for no limit (server mode only)")
+		policy      = flag.String("policy", cache.PolicyLRU, "Eviction policy: "+strings.Join(cache.Policies, ", ")+" (server mode only)")
+		admission   = flag.Bool("admission", false, "Enable the TinyLFU admission filter for scan resistance (server mode only)")
+		maxMemory   = flag.String("maxmemory", "0", "Cache memory limit such as 512mb or 1gb, 0 for no limit (server mode only)")
+		maxPinned   = flag.Float64("max-pinned", cache.DefaultMaxPinnedShare, "Share of capacity and maxmemory pinned keys may use, up to 1 (server mode only)")
+		namespaces  = flag.Int("namespaces", cache.DefaultMaxNamespaces, "Max number of namespaces, each with its own capacity and maxmemory (server mode only)")
 		interactive = flag.Bool("interactive", false, "Interactive client mode")
 		command     = flag.String("cmd", "", "Single command to execute (client mode)")
 	)
@@ -22,7 +31,32 @@ func main() {
 
 	switch *mode {
 	case "server":
-		runServer(*address, *capacity)
+		maxBytes, err := parseBytes(*maxMemory)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid maxmemory: %v\n", err)
+			os.Exit(1)
+		}
+		if !slices.Contains(cache.Policies, *policy) {
+			fmt.Fprintf(os.Stderr, "Invalid policy: %s. Use one of %s\n", *policy, strings.Join(cache.Policies, ", "))
+			os.Exit(1)
+		}
+		if *capacity < 0 {
+			fmt.Fprintln(os.Stderr, "Capacity must not be negative")
+			os.Exit(1)
+		}
+		if *capacity == 0 && maxBytes == 0 {
+			fmt.Fprintln(os.Stderr, "Either capacity or maxmemory must be greater than 0")
+			os.Exit(1)
+		}
+		if *maxPinned <= 0 || *maxPinned > 1 {
+			fmt.Fprintln(os.Stderr, "Max pinned share must be greater than 0 and at most 1")
+			os.Exit(1)
+		}
+		if *namespaces <= 0 {
+			fmt.Fprintln(os.Stderr, "Namespaces must be greater than 0")
+			os.Exit(1)
+		}
+		runServer(*address, *capacity, maxBytes, *policy, *admission, *maxPinned, *namespaces)
 	case "client":
 		runClient(*address, *interactive, *command)
 	default:
@@ -51,12 +85,52 @@ func runClient(address string, Interactive bool, command string) {
 	}
 }
 
-func runServer(address string, capacity int) {
+// parseBytes parses a size like 1024, 64kb, 512mb or 1gb into bytes
+func parseBytes(size string) (int64, error) {
+	size = strings.ToLower(strings.TrimSpace(size))
+
+	multiplier := int64(1)
+	for _, unit := range []struct {
+		suffix string
+		factor int64
+	}{{"kb", 1 << 10}, {"mb", 1 << 20}, {"gb", 1 << 30}, {"b", 1}} {
+		if strings.HasSuffix(size, unit.suffix) {
+			size = strings.TrimSuffix(size, unit.suffix)
+			multiplier = unit.factor
+			break
+		}
+	}
+
+	n, err := strconv.ParseInt(size, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	if n > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("size %q is too large", size)
+	}
+	return n * multiplier, nil
+}
+
+func runServer(address string, capacity int, maxMemory int64, policy string, admission bool, maxPinned float64, namespaces int) {
 	fmt.Printf("Starting GCache Server...\n")
 	fmt.Printf("Address: %s\n", address)
 	fmt.Printf("Capacity: %d\n", capacity)
-
-	server := cache.NewServer(address, capacity)
+	fmt.Printf("Max memory: %d bytes\n", maxMemory)
+	fmt.Printf("Eviction policy: %s\n", policy)
+	fmt.Printf("Admission filter: %t\n", admission)
+	fmt.Printf("Max pinned share: %.2f\n", maxPinned)
+	fmt.Printf("Max namespaces: %d\n", namespaces)
+
+	server := cache.NewServerWithConfig(cache.ServerConfig{
+		Address:       address,
+		Capacity:      capacity,
+		MaxMemory:     maxMemory,
+		Policy:        policy,
+		Admission:     admission,
+		MaxNamespaces: namespaces,
+
+		MaxPinnedShare: maxPinned,
+	})
 	if err := server.Start(); err != nil {
 		log.Fatalf("Server failed: %v", err)
 	}
@@ -2,9 +2,13 @@ package cache
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
+	"iter"
 	"net"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -15,6 +19,11 @@ type Client struct {
 	writer *bufio.Writer
 }
 
+// ErrAmbiguousValue is returned by the Set methods for a value the server
+// would not store as a whole: its last words read as SET options, such as
+// "my PIN" or "wait EX 10", or for SetTagged it has a TAGS word
+var ErrAmbiguousValue = errors.New("value ends with words that read as SET options")
+
 func NewClient(address string) (*Client, error) {
 	conn, err := net.DialTimeout("tcp", address, 5*time.Second)
 	if err != nil {
@@ -45,22 +54,60 @@ func (c *Client) SendCommand(command string) (string, error) {
 	}
 
 	//read response
-	response, err := c.reader.ReadString('\n')
+	response, err := c.readLine()
 	if err != nil {
-		return "", fmt.Errorf("Failed to read response: %v", err)
+		return "", err
 	}
 
-	response = strings.TrimSpace(response)
+	// array replies continue with one line per element, kept one per line
+	if strings.HasPrefix(response, "*") {
+		count, err := strconv.Atoi(response[1:])
+		if err != nil {
+			return "", fmt.Errorf("invalid array response: %s", response)
+		}
 
-	if strings.Contains(response, "\r\n") {
-		// For now, just replace \r\n with spaces for single-line display
-		response = strings.ReplaceAll(response, "\r\n", " ")
+		lines := []string{response}
+		for i := 0; i < count; i++ {
+			line, err := c.readLine()
+			if err != nil {
+				return "", err
+			}
+			lines = append(lines, line)
+		}
+		response = strings.Join(lines, "\n")
 	}
 
 	return response, nil
 
 }
 
+func (c *Client) readLine() (string, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("Failed to read response: %v", err)
+	}
+	return strings.TrimSpace(line), nil
+}
+
+// parseArray splits an array reply into its elements. ok is false for nil elements.
+func parseArray(response string) (elements []string, ok []bool, err error) {
+	if strings.HasPrefix(response, "-") {
+		return nil, nil, replyError(response)
+	}
+	if !strings.HasPrefix(response, "*") {
+		return nil, nil, fmt.Errorf("unexpected response: %s", response)
+	}
+
+	lines := strings.Split(response, "\n")[1:]
+	elements, ok = make([]string, len(lines)), make([]bool, len(lines))
+	for i, line := range lines {
+		if line != nilElement {
+			elements[i], ok[i] = strings.TrimPrefix(line, "+"), true
+		}
+	}
+	return elements, ok, nil
+}
+
 func (c *Client) Get(key string) (string, error) {
 	response, err := c.SendCommand(fmt.Sprintf("GET %s", key))
 	if err != nil {
@@ -69,14 +116,47 @@ func (c *Client) Get(key string) (string, error) {
 
 	if strings.HasPrefix(response, "+") {
 		return response[1:], nil
-	} else if strings.HasPrefix(response, "-ERR") {
-		return "", fmt.Errorf("%s", response[5:])
+	} else if strings.HasPrefix(response, "-") {
+		return "", replyError(response)
 	}
 
 	return "", fmt.Errorf("unexpected response : %s", response)
 }
 
+// Gets returns the value and its version for CAS, or ErrNotFound
+func (c *Client) Gets(key string) (string, uint64, error) {
+	response, err := c.SendCommand(fmt.Sprintf("GETS %s", key))
+	if err != nil {
+		return "", 0, err
+	}
+
+	if strings.HasPrefix(response, "+") {
+		versionField, value, _ := strings.Cut(response[1:], " ")
+		version, err := strconv.ParseUint(versionField, 10, 64)
+		if err != nil {
+			return "", 0, fmt.Errorf("invalid version in response: %s", response)
+		}
+		return value, version, nil
+	} else if strings.HasPrefix(response, "-") {
+		return "", 0, replyError(response)
+	}
+
+	return "", 0, fmt.Errorf("unexpected response: %s", response)
+}
+
+// CAS sets the value only if the key still has the version returned by Gets,
+// and returns the new version. It fails with ErrVersionConflict if the key was
+// written meanwhile and with ErrNotFound if it is gone.
+func (c *Client) CAS(key, value string, version uint64) (uint64, error) {
+	n, err := c.sendInteger(fmt.Sprintf("CAS %s %d %s", key, version, value))
+	return uint64(n), err
+}
+
 func (c *Client) Set(key, value string) error {
+	if err := checkSetValue(value); err != nil {
+		return err
+	}
+
 	response, err := c.SendCommand(fmt.Sprintf("SET %s %s", key, value))
 	if err != nil {
 		return err
@@ -89,6 +169,118 @@ func (c *Client) Set(key, value string) error {
 	return fmt.Errorf("unexpected response: %s", response)
 }
 
+// SetWithTTL stores the value with an expiration, rounded down to milliseconds
+func (c *Client) SetWithTTL(key, value string, ttl time.Duration) error {
+	if err := checkSetValue(value); err != nil {
+		return err
+	}
+	return c.sendOK(fmt.Sprintf("SET %s %s PX %d", key, value, ttl.Milliseconds()))
+}
+
+// SetPinned stores the value and pins it, so it is never evicted
+func (c *Client) SetPinned(key, value string) error {
+	if err := checkSetValue(value); err != nil {
+		return err
+	}
+	return c.sendOK(fmt.Sprintf("SET %s %s PIN", key, value))
+}
+
+// checkSetValue returns ErrAmbiguousValue if the server would read the last
+// words of value as SET options
+func checkSetValue(value string) error {
+	words := strings.Fields(value)
+	if rest, _, err := parseSetOptions(words); err != nil || len(rest) != len(words) {
+		return ErrAmbiguousValue
+	}
+	return nil
+}
+
+// Pin keeps key from being evicted and reports whether it exists
+func (c *Client) Pin(key string) (bool, error) {
+	n, err := c.sendInteger(fmt.Sprintf("PIN %s", key))
+	return n == 1, err
+}
+
+// Unpin makes key evictable again and reports whether it was pinned
+func (c *Client) Unpin(key string) (bool, error) {
+	n, err := c.sendInteger(fmt.Sprintf("UNPIN %s", key))
+	return n == 1, err
+}
+
+// SetTagged stores key with tags, so it can be removed with Invalidate
+func (c *Client) SetTagged(key, value string, tags ...string) error {
+	if err := checkSetValue(value); err != nil {
+		return err
+	}
+	if words := strings.Fields(value); slices.ContainsFunc(words[min(1, len(words)):], func(word string) bool {
+		return strings.ToUpper(word) == "TAGS"
+	}) {
+		return ErrAmbiguousValue
+	}
+	return c.sendOK(fmt.Sprintf("SETTAGGED %s %s TAGS %s", key, value, strings.Join(tags, " ")))
+}
+
+// Invalidate removes every key tagged with tag and returns how many were removed
+func (c *Client) Invalidate(tag string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("INVALIDATE %s", tag))
+}
+
+// Incr adds 1 to the integer at key and returns the result, a missing key counts as 0
+func (c *Client) Incr(key string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("INCR %s", key))
+}
+
+func (c *Client) Decr(key string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("DECR %s", key))
+}
+
+func (c *Client) IncrBy(key string, delta int64) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("INCRBY %s %d", key, delta))
+}
+
+func (c *Client) DecrBy(key string, delta int64) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("DECRBY %s %d", key, delta))
+}
+
+func (c *Client) IncrByFloat(key string, delta float64) (float64, error) {
+	return c.sendFloat(fmt.Sprintf("INCRBYFLOAT %s %s", key, formatFloat(delta)))
+}
+
+// Expire reports whether the key existed and got the expiration set
+func (c *Client) Expire(key string, ttl time.Duration) (bool, error) {
+	n, err := c.sendInteger(fmt.Sprintf("PEXPIRE %s %d", key, ttl.Milliseconds()))
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// Persist reports whether an expiration was removed from the key
+func (c *Client) Persist(key string) (bool, error) {
+	n, err := c.sendInteger(fmt.Sprintf("PERSIST %s", key))
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// TTL returns the remaining time to live of the key,
+// or NoExpiration if the key exists without an expiration
+func (c *Client) TTL(key string) (time.Duration, error) {
+	n, err := c.sendInteger(fmt.Sprintf("PTTL %s", key))
+	if err != nil {
+		return 0, err
+	}
+
+	switch n {
+	case -2:
+		return 0, ErrNotFound
+	case -1:
+		return NoExpiration, nil
+	}
+	return time.Duration(n) * time.Millisecond, nil
+}
+
 func (c *Client) Delete(key string) error {
 	response, err := c.SendCommand(fmt.Sprintf("DEL %s", key))
 	if err != nil {
@@ -121,6 +313,47 @@ func (c *Client) Size() (int, error) {
 
 }
 
+// sendInteger sends the command and parses a ':' integer reply
+func (c *Client) sendInteger(command string) (int64, error) {
+	response, err := c.SendCommand(command)
+	if err != nil {
+		return 0, err
+	}
+
+	if strings.HasPrefix(response, ":") {
+		n, err := strconv.ParseInt(response[1:], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid integer response: %s", response)
+		}
+		return n, nil
+	} else if strings.HasPrefix(response, "-") {
+		return 0, replyError(response)
+	}
+
+	return 0, fmt.Errorf("unexpected response: %s", response)
+}
+
+// replyError turns an error reply into an error, matching ErrNotFound,
+// ErrVersionConflict and ErrWrongType with errors.Is
+func replyError(response string) error {
+	if strings.HasPrefix(response, "-WRONGTYPE") {
+		return ErrWrongType
+	}
+
+	switch message := strings.TrimPrefix(response, "-ERR "); message {
+	case ErrNotFound.Error():
+		return ErrNotFound
+	case ErrVersionConflict.Error():
+		return ErrVersionConflict
+	case ErrPinLimit.Error():
+		return ErrPinLimit
+	case ErrPinnedFull.Error():
+		return ErrPinnedFull
+	default:
+		return fmt.Errorf("%s", message)
+	}
+}
+
 func (c *Client) Ping() error {
 	response, err := c.SendCommand("PING")
 	if err != nil {
@@ -134,6 +367,17 @@ func (c *Client) Ping() error {
 	return fmt.Errorf("unexpected response: %s", response)
 }
 
+// Select switches the connection to a namespace, created on first use.
+// Commands then only see the keys of that namespace.
+func (c *Client) Select(namespace string) error {
+	return c.sendOK(fmt.Sprintf("SELECT %s", namespace))
+}
+
+// FlushAll clears every namespace, Clear only the selected one
+func (c *Client) FlushAll() error {
+	return c.sendOK("FLUSHALL")
+}
+
 func (c *Client) Clear() error {
 	response, err := c.SendCommand("CLEAR")
 	if err != nil {
@@ -160,21 +404,654 @@ func (c *Client) Info() (string, error) {
 	return "", fmt.Errorf("unexpected response: %s", response)
 }
 
+// ServerStats is the parsed reply of the STATS command
+type ServerStats struct {
+	Size       int
+	Capacity   int
+	UsedMemory int64
+	MaxMemory  int64
+	HitRatio   float64
+	Stats
+
+	Pinned      int     // pinned keys
+	PinnedShare float64 // share of the capacity or memory they use
+}
+
+func (c *Client) Stats() (ServerStats, error) {
+	response, err := c.SendCommand("STATS")
+	if err != nil {
+		return ServerStats{}, err
+	}
+
+	if !strings.HasPrefix(response, "+") {
+		return ServerStats{}, fmt.Errorf("unexpected response: %s", response)
+	}
+
+	var stats ServerStats
+	ints := map[string]*int64{"used_memory": &stats.UsedMemory, "maxmemory": &stats.MaxMemory}
+	counters := map[string]*uint64{
+		"hits":               &stats.Hits,
+		"misses":             &stats.Misses,
+		"sets":               &stats.Sets,
+		"deletes":            &stats.Deletes,
+		"evictions":          &stats.Evictions,
+		"expirations":        &stats.Expirations,
+		"admission_accepted": &stats.AdmissionAccepted,
+		"admission_rejected": &stats.AdmissionRejected,
+		"stale_hits":         &stats.StaleHits,
+		"refreshes":          &stats.Refreshes,
+		"refresh_failures":   &stats.RefreshFailures,
+	}
+
+	for _, field := range strings.Fields(response[1:]) {
+		name, value, ok := strings.Cut(field, ":")
+		if !ok {
+			continue
+		}
+
+		var err error
+		switch name {
+		case "size":
+			stats.Size, err = strconv.Atoi(value)
+		case "capacity":
+			stats.Capacity, err = strconv.Atoi(value)
+		case "hit_ratio":
+			stats.HitRatio, err = strconv.ParseFloat(value, 64)
+		case "pinned":
+			stats.Pinned, err = strconv.Atoi(value)
+		case "pinned_share":
+			stats.PinnedShare, err = strconv.ParseFloat(value, 64)
+		default:
+			if dst, ok := ints[name]; ok {
+				*dst, err = strconv.ParseInt(value, 10, 64)
+			} else if dst, ok := counters[name]; ok {
+				*dst, err = strconv.ParseUint(value, 10, 64)
+			}
+			// unknown fields are skipped so newer servers stay compatible
+		}
+		if err != nil {
+			return ServerStats{}, fmt.Errorf("invalid stats field %s: %v", field, err)
+		}
+	}
+
+	return stats, nil
+}
+
+func (c *Client) ResetStats() error {
+	response, err := c.SendCommand("STATS RESET")
+	if err != nil {
+		return err
+	}
+
+	if response == "+OK" {
+		return nil
+	}
+
+	return fmt.Errorf("unexpected response: %s", response)
+}
+
+// ConfigGet returns a server setting, see CONFIG GET
+func (c *Client) ConfigGet(parameter string) (string, error) {
+	response, err := c.SendCommand("CONFIG GET " + parameter)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasPrefix(response, "+") || strings.HasPrefix(response, ":") {
+		return response[1:], nil
+	} else if strings.HasPrefix(response, "-") {
+		return "", replyError(response)
+	}
+
+	return "", fmt.Errorf("unexpected response: %s", response)
+}
+
+// ConfigSet changes a server setting at runtime, only capacity is supported
+func (c *Client) ConfigSet(parameter, value string) error {
+	return c.sendOK(fmt.Sprintf("CONFIG SET %s %s", parameter, value))
+}
+
+// sendArray sends a command answered with an array and returns its elements
+func (c *Client) sendArray(command string) ([]string, error) {
+	response, err := c.SendCommand(command)
+	if err != nil {
+		return nil, err
+	}
+
+	elements, _, err := parseArray(response)
+	return elements, err
+}
+
+// sendFloat sends a command answered with a number as a simple string
+func (c *Client) sendFloat(command string) (float64, error) {
+	response, err := c.SendCommand(command)
+	if err != nil {
+		return 0, err
+	}
+
+	if strings.HasPrefix(response, "+") {
+		f, err := strconv.ParseFloat(response[1:], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid float response: %s", response)
+		}
+		return f, nil
+	} else if strings.HasPrefix(response, "-") {
+		return 0, replyError(response)
+	}
+
+	return 0, fmt.Errorf("unexpected response: %s", response)
+}
+
+// sendString sends a command answered with a simple string
+func (c *Client) sendString(command string) (string, error) {
+	response, err := c.SendCommand(command)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasPrefix(response, "+") {
+		return response[1:], nil
+	} else if strings.HasPrefix(response, "-") {
+		return "", replyError(response)
+	}
+
+	return "", fmt.Errorf("unexpected response: %s", response)
+}
+
+// sendOK sends the command and expects a '+OK' reply
+func (c *Client) sendOK(command string) error {
+	response, err := c.SendCommand(command)
+	if err != nil {
+		return err
+	}
+
+	if response == "+OK" {
+		return nil
+	} else if strings.HasPrefix(response, "-") {
+		return replyError(response)
+	}
+
+	return fmt.Errorf("unexpected response: %s", response)
+}
+
+// HSet sets the fields of the hash at key and returns how many were new
+func (c *Client) HSet(key string, fields map[string]string) (int64, error) {
+	command := []string{"HSET", key}
+	for field, value := range fields {
+		command = append(command, field, value)
+	}
+	return c.sendInteger(strings.Join(command, " "))
+}
+
+// HGet returns a field of the hash at key
+func (c *Client) HGet(key, field string) (string, error) {
+	response, err := c.SendCommand(fmt.Sprintf("HGET %s %s", key, field))
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasPrefix(response, "+") {
+		return response[1:], nil
+	} else if strings.HasPrefix(response, "-") {
+		return "", replyError(response)
+	}
+
+	return "", fmt.Errorf("unexpected response: %s", response)
+}
+
+// HMGet returns the given fields of the hash at key, missing fields are left out
+func (c *Client) HMGet(key string, fields ...string) (map[string]string, error) {
+	response, err := c.SendCommand(fmt.Sprintf("HMGET %s %s", key, strings.Join(fields, " ")))
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok, err := parseArray(response)
+	if err != nil {
+		return nil, err
+	}
+	if len(values) != len(fields) {
+		return nil, fmt.Errorf("expected %d values, got %d", len(fields), len(values))
+	}
+
+	result := make(map[string]string, len(fields))
+	for i, field := range fields {
+		if ok[i] {
+			result[field] = values[i]
+		}
+	}
+	return result, nil
+}
+
+// HGetAll returns the hash at key, empty if the key is missing
+func (c *Client) HGetAll(key string) (map[string]string, error) {
+	response, err := c.SendCommand(fmt.Sprintf("HGETALL %s", key))
+	if err != nil {
+		return nil, err
+	}
+
+	elements, _, err := parseArray(response)
+	if err != nil {
+		return nil, err
+	}
+	if len(elements)%2 != 0 {
+		return nil, fmt.Errorf("odd number of elements in response: %s", response)
+	}
+
+	hash := make(map[string]string, len(elements)/2)
+	for i := 0; i < len(elements); i += 2 {
+		hash[elements[i]] = elements[i+1]
+	}
+	return hash, nil
+}
+
+// HDel removes fields from the hash at key and returns how many existed
+func (c *Client) HDel(key string, fields ...string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("HDEL %s %s", key, strings.Join(fields, " ")))
+}
+
+func (c *Client) HLen(key string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("HLEN %s", key))
+}
+
+func (c *Client) HExists(key, field string) (bool, error) {
+	n, err := c.sendInteger(fmt.Sprintf("HEXISTS %s %s", key, field))
+	return n == 1, err
+}
+
+// HIncrBy adds delta to the integer in a field of the hash at key and returns the result
+func (c *Client) HIncrBy(key, field string, delta int64) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("HINCRBY %s %s %d", key, field, delta))
+}
+
+// LPush inserts values at the head of the list at key, in order, so the last
+// one ends up first. It returns the length of the list.
+func (c *Client) LPush(key string, values ...string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("LPUSH %s %s", key, strings.Join(values, " ")))
+}
+
+// RPush appends values to the list at key and returns the length of the list
+func (c *Client) RPush(key string, values ...string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("RPUSH %s %s", key, strings.Join(values, " ")))
+}
+
+// LPop removes and returns the head of the list at key
+func (c *Client) LPop(key string) (string, error) {
+	return c.sendString(fmt.Sprintf("LPOP %s", key))
+}
+
+// RPop removes and returns the tail of the list at key
+func (c *Client) RPop(key string) (string, error) {
+	return c.sendString(fmt.Sprintf("RPOP %s", key))
+}
+
+// BLPop pops the head of the first non-empty list of keys, waiting up to
+// timeout for a push if they are all empty. A timeout of 0 waits forever.
+// ok is false if the timeout fired.
+func (c *Client) BLPop(timeout time.Duration, keys ...string) (key, value string, ok bool, err error) {
+	return c.blockingPop("BLPOP", timeout, keys)
+}
+
+// BRPop is BLPop popping the tail of the list
+func (c *Client) BRPop(timeout time.Duration, keys ...string) (key, value string, ok bool, err error) {
+	return c.blockingPop("BRPOP", timeout, keys)
+}
+
+func (c *Client) blockingPop(command string, timeout time.Duration, keys []string) (string, string, bool, error) {
+	seconds := strconv.FormatFloat(timeout.Seconds(), 'f', -1, 64)
+	response, err := c.SendCommand(fmt.Sprintf("%s %s %s", command, strings.Join(keys, " "), seconds))
+	if err != nil {
+		return "", "", false, err
+	}
+	if response == "$-1" {
+		return "", "", false, nil
+	}
+
+	elements, _, err := parseArray(response)
+	if err != nil {
+		return "", "", false, err
+	}
+	if len(elements) != 2 {
+		return "", "", false, fmt.Errorf("unexpected response: %s", response)
+	}
+	return elements[0], elements[1], true, nil
+}
+
+// LRange returns the elements of the list at key from start to stop, both
+// included. Negative indexes count from the tail, -1 being the last element.
+func (c *Client) LRange(key string, start, stop int) ([]string, error) {
+	return c.sendArray(fmt.Sprintf("LRANGE %s %d %d", key, start, stop))
+}
+
+func (c *Client) LLen(key string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("LLEN %s", key))
+}
+
+// LTrim keeps only the elements of the list at key from start to stop
+func (c *Client) LTrim(key string, start, stop int) error {
+	return c.sendOK(fmt.Sprintf("LTRIM %s %d %d", key, start, stop))
+}
+
+// SAdd adds members to the set at key and returns how many were new
+func (c *Client) SAdd(key string, members ...string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("SADD %s %s", key, strings.Join(members, " ")))
+}
+
+// SRem removes members from the set at key and returns how many existed
+func (c *Client) SRem(key string, members ...string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("SREM %s %s", key, strings.Join(members, " ")))
+}
+
+func (c *Client) SIsMember(key, member string) (bool, error) {
+	n, err := c.sendInteger(fmt.Sprintf("SISMEMBER %s %s", key, member))
+	return n == 1, err
+}
+
+// SMembers returns the members of the set at key, sorted
+func (c *Client) SMembers(key string) ([]string, error) {
+	return c.sendArray(fmt.Sprintf("SMEMBERS %s", key))
+}
+
+func (c *Client) SCard(key string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("SCARD %s", key))
+}
+
+// SPop removes and returns up to count random members of the set at key
+func (c *Client) SPop(key string, count int) ([]string, error) {
+	return c.sendArray(fmt.Sprintf("SPOP %s %d", key, count))
+}
+
+// SRandMember returns up to count distinct random members of the set at key.
+// A negative count returns -count members that may repeat, at most 65536.
+func (c *Client) SRandMember(key string, count int) ([]string, error) {
+	return c.sendArray(fmt.Sprintf("SRANDMEMBER %s %d", key, count))
+}
+
+// SInter returns the members found in all the sets at keys
+func (c *Client) SInter(keys ...string) ([]string, error) {
+	return c.sendArray(fmt.Sprintf("SINTER %s", strings.Join(keys, " ")))
+}
+
+// SUnion returns the members found in any of the sets at keys
+func (c *Client) SUnion(keys ...string) ([]string, error) {
+	return c.sendArray(fmt.Sprintf("SUNION %s", strings.Join(keys, " ")))
+}
+
+// SDiff returns the members of the first set not found in the others
+func (c *Client) SDiff(keys ...string) ([]string, error) {
+	return c.sendArray(fmt.Sprintf("SDIFF %s", strings.Join(keys, " ")))
+}
+
+// SInterStore stores the intersection of the sets at keys in destination
+// and returns its size
+func (c *Client) SInterStore(destination string, keys ...string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("SINTERSTORE %s %s", destination, strings.Join(keys, " ")))
+}
+
+// SUnionStore stores the union of the sets at keys in destination and
+// returns its size
+func (c *Client) SUnionStore(destination string, keys ...string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("SUNIONSTORE %s %s", destination, strings.Join(keys, " ")))
+}
+
+// SDiffStore stores the difference of the sets at keys in destination and
+// returns its size
+func (c *Client) SDiffStore(destination string, keys ...string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("SDIFFSTORE %s %s", destination, strings.Join(keys, " ")))
+}
+
+// ScoredMember is a member of a sorted set with its score
+type ScoredMember struct {
+	Member string
+	Score  float64
+}
+
+// ZAdd sets the scores of members of the sorted set at key and returns how
+// many members were new
+func (c *Client) ZAdd(key string, scores map[string]float64) (int64, error) {
+	command := []string{"ZADD", key}
+	for member, score := range scores {
+		command = append(command, formatFloat(score), member)
+	}
+	return c.sendInteger(strings.Join(command, " "))
+}
+
+// ZRem removes members from the sorted set at key and returns how many existed
+func (c *Client) ZRem(key string, members ...string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("ZREM %s %s", key, strings.Join(members, " ")))
+}
+
+func (c *Client) ZScore(key, member string) (float64, error) {
+	return c.sendFloat(fmt.Sprintf("ZSCORE %s %s", key, member))
+}
+
+// ZIncrBy adds delta to the score of member, 0 if missing, and returns the new score
+func (c *Client) ZIncrBy(key, member string, delta float64) (float64, error) {
+	return c.sendFloat(fmt.Sprintf("ZINCRBY %s %s %s", key, formatFloat(delta), member))
+}
+
+// ZRank returns the 0-based position of member by ascending score
+func (c *Client) ZRank(key, member string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("ZRANK %s %s", key, member))
+}
+
+func (c *Client) ZCard(key string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("ZCARD %s", key))
+}
+
+// ZRange returns the members of the sorted set at key from rank start to
+// stop by ascending score. Negative ranks count from the end.
+func (c *Client) ZRange(key string, start, stop int) ([]string, error) {
+	return c.sendArray(fmt.Sprintf("ZRANGE %s %d %d", key, start, stop))
+}
+
+// ZRevRange is ZRange by descending score
+func (c *Client) ZRevRange(key string, start, stop int) ([]string, error) {
+	return c.sendArray(fmt.Sprintf("ZREVRANGE %s %d %d", key, start, stop))
+}
+
+// ZRangeWithScores is ZRange returning the scores too
+func (c *Client) ZRangeWithScores(key string, start, stop int) ([]ScoredMember, error) {
+	return c.sendScoredArray(fmt.Sprintf("ZRANGE %s %d %d WITHSCORES", key, start, stop))
+}
+
+// ZRevRangeWithScores is ZRevRange returning the scores too
+func (c *Client) ZRevRangeWithScores(key string, start, stop int) ([]ScoredMember, error) {
+	return c.sendScoredArray(fmt.Sprintf("ZREVRANGE %s %d %d WITHSCORES", key, start, stop))
+}
+
+// ZRangeByScore returns the members with a score between min and max by
+// ascending score. Bounds are included unless prefixed by "(", and may be
+// "-inf" or "+inf".
+func (c *Client) ZRangeByScore(key, min, max string) ([]ScoredMember, error) {
+	return c.sendScoredArray(fmt.Sprintf("ZRANGEBYSCORE %s %s %s WITHSCORES", key, min, max))
+}
+
+// sendScoredArray sends a command answered with members and scores alternating
+func (c *Client) sendScoredArray(command string) ([]ScoredMember, error) {
+	elements, err := c.sendArray(command)
+	if err != nil {
+		return nil, err
+	}
+	if len(elements)%2 != 0 {
+		return nil, fmt.Errorf("odd number of elements in response to %s", command)
+	}
+
+	members := make([]ScoredMember, len(elements)/2)
+	for i := range members {
+		score, err := strconv.ParseFloat(elements[2*i+1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid score: %s", elements[2*i+1])
+		}
+		members[i] = ScoredMember{Member: elements[2*i], Score: score}
+	}
+	return members, nil
+}
+
+// Keys returns the keys matching a glob pattern such as "user:*", sorted.
+// Prefer Scan on large caches.
+func (c *Client) Keys(pattern string) ([]string, error) {
+	return c.sendArray(fmt.Sprintf("KEYS %s", pattern))
+}
+
+// Scan iterates over the keys matching a glob pattern, "*" for all, fetching
+// them count at a time with SCAN. Keys present for the whole iteration are
+// returned once. Iteration stops at the first error, which is yielded with an
+// empty key.
+func (c *Client) Scan(pattern string, count int) iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		cursor := "0"
+		for {
+			elements, err := c.sendArray(fmt.Sprintf("SCAN %s MATCH %s COUNT %d", cursor, pattern, count))
+			if err == nil && len(elements) == 0 {
+				err = fmt.Errorf("missing cursor in SCAN response")
+			}
+			if err != nil {
+				yield("", err)
+				return
+			}
+
+			for _, key := range elements[1:] {
+				if !yield(key, nil) {
+					return
+				}
+			}
+
+			cursor = elements[0]
+			if cursor == "0" {
+				return
+			}
+		}
+	}
+}
+
+// IdleTime returns how long key has not been read or written, in whole seconds
+func (c *Client) IdleTime(key string) (time.Duration, error) {
+	n, err := c.sendInteger(fmt.Sprintf("OBJECT IDLETIME %s", key))
+	return time.Duration(n) * time.Second, err
+}
+
+// Freq returns the number of reads and writes of key
+func (c *Client) Freq(key string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("OBJECT FREQ %s", key))
+}
+
+// MemoryUsage returns the approximate bytes used by key and its value
+func (c *Client) MemoryUsage(key string) (int64, error) {
+	return c.sendInteger(fmt.Sprintf("MEMORY USAGE %s", key))
+}
+
+// Coldest returns up to n keys in the order they would be evicted
+func (c *Client) Coldest(n int) ([]string, error) {
+	return c.sendArray(fmt.Sprintf("DEBUG LRU %d", n))
+}
+
+// Type returns the type of the value at key, "none" if the key is missing
+func (c *Client) Type(key string) (string, error) {
+	response, err := c.SendCommand(fmt.Sprintf("TYPE %s", key))
+	if err != nil {
+		return "", err
+	}
+
+	if strings.HasPrefix(response, "+") {
+		return response[1:], nil
+	} else if strings.HasPrefix(response, "-") {
+		return "", replyError(response)
+	}
+
+	return "", fmt.Errorf("unexpected response: %s", response)
+}
+
 func printHelp() {
 	help := `
 Available Commands:
   GET key          - Get value for key
-  SET key value    - Set key to value
+  SET key value [EX s|PX ms|EXAT ts|PXAT ts] [PIN]
+                   - Set key to value, optionally with expiration, pinned
+                     keys are never evicted. Options must be upper case.
+  PIN key          - Keep key from being evicted
+  UNPIN key        - Make key evictable again
+  SETTAGGED key value [EX s|...] TAGS tag...
+                   - Set key with tags
+  INVALIDATE tag   - Delete all keys with the tag
   DEL key          - Delete key
-  SIZE             - Get cache size
-  CLEAR            - Clear all items
+  GETS key         - Get version and value of key
+  CAS key version value
+                   - Set key only if its version is unchanged
+  INCR key         - Add 1 to the integer at key (DECR subtracts 1)
+  INCRBY key n     - Add n to the integer at key (DECRBY subtracts n)
+  INCRBYFLOAT key f
+                   - Add f to the number at key
+  HSET key field value [field value ...]
+                   - Set hash fields
+  HGET key field   - Get a hash field (HMGET key field... for several)
+  HGETALL key      - Get all fields and values of a hash
+  HDEL key field...
+                   - Delete hash fields
+  HLEN key         - Number of fields in a hash
+  HEXISTS key field
+                   - Check if a hash field exists
+  HINCRBY key field n
+                   - Add n to the integer in a hash field
+  LPUSH key value...
+                   - Insert values at the head of a list (RPUSH at the tail)
+  LPOP key         - Remove and get the head of a list (RPOP the tail)
+  BLPOP key... timeout
+                   - LPOP, waiting up to timeout seconds for a push, 0 forever (BRPOP the tail)
+  LRANGE key start stop
+                   - Get list elements, negative indexes count from the tail
+  LLEN key         - Length of a list
+  LTRIM key start stop
+                   - Keep only the given range of a list
+  SADD key member...
+                   - Add members to a set (SREM removes them)
+  SISMEMBER key member
+                   - Check if a member is in a set
+  SMEMBERS key     - Get all members of a set
+  SCARD key        - Number of members in a set
+  SPOP key [count] - Remove and get random members (SRANDMEMBER keeps them)
+  SINTER key...    - Members in all sets (SUNION in any, SDIFF only in the first)
+  SINTERSTORE dest key...
+                   - Store SINTER in dest (also SUNIONSTORE, SDIFFSTORE)
+  ZADD key score member [score member ...]
+                   - Set member scores in a sorted set (ZREM removes members)
+  ZSCORE key member
+                   - Get the score of a member
+  ZINCRBY key n member
+                   - Add n to the score of a member
+  ZRANK key member - Position of a member by ascending score
+  ZRANGE key start stop [WITHSCORES]
+                   - Members by rank, ascending (ZREVRANGE descending)
+  ZRANGEBYSCORE key min max [WITHSCORES] [LIMIT offset count]
+                   - Members with a score in range, "(" excludes a bound
+  ZCARD key        - Number of members in a sorted set
+  TYPE key         - Type of the value at key
+  OBJECT IDLETIME key
+                   - Seconds since key was last read or written
+  OBJECT FREQ key  - Number of reads and writes of key
+  MEMORY USAGE key - Approximate bytes used by key and its value
+  DEBUG LRU n      - The n coldest keys, next to be evicted first
+  KEYS pattern     - All keys matching a glob pattern such as user:*
+  SCAN cursor [MATCH pattern] [COUNT n]
+                   - Next keys from cursor, 0 to start; the reply starts with the next cursor
+  EXPIRE key s     - Set key expiration in seconds (PEXPIRE in ms)
+  TTL key          - Remaining seconds to live (PTTL in ms), -1 no expiry, -2 missing
+  PERSIST key      - Remove key expiration
+  SELECT namespace - Switch to a namespace, "0" at connection
+  SIZE             - Get size of the namespace
+  CLEAR            - Clear all items of the namespace
+  FLUSHALL         - Clear all namespaces
   PING [message]   - Ping server
   INFO             - Server information
-  STATS            - Cache statistics
+  STATS [RESET]    - Cache statistics, or reset the counters
+  CONFIG GET param - Get capacity, maxmemory or eviction_policy
+  CONFIG SET capacity n
+                   - Resize the cache, shrinking evicts the excess items
   QUIT/EXIT        - Exit client
 
 Examples:
   SET mykey "hello world"
+  SET session abc EX 60
   GET mykey
   DEL mykey
 `
@@ -183,7 +1060,7 @@ Examples:
 
 func (c *Client) InteractiveMode() {
 	fmt.Println("GCache Client - Interactive Mode")
-	fmt.Println("Commands: GET, SET, DEL, SIZE, CLEAR, PING, INFO, STATS, QUIT")
+	fmt.Println("Commands: GET, SET, PIN, UNPIN, SETTAGGED, INVALIDATE, DEL, GETS, CAS, INCR, DECR, INCRBY, DECRBY, INCRBYFLOAT, HSET, HGET, HMGET, HGETALL, HDEL, HLEN, HEXISTS, HINCRBY, LPUSH, RPUSH, LPOP, RPOP, BLPOP, BRPOP, LRANGE, LLEN, LTRIM, SADD, SREM, SISMEMBER, SMEMBERS, SCARD, SPOP, SRANDMEMBER, SINTER, SUNION, SDIFF, SINTERSTORE, SUNIONSTORE, SDIFFSTORE, ZADD, ZREM, ZSCORE, ZINCRBY, ZRANK, ZRANGE, ZREVRANGE, ZRANGEBYSCORE, ZCARD, TYPE, OBJECT, MEMORY, DEBUG, KEYS, SCAN, EXPIRE, TTL, PERSIST, SELECT, SIZE, CLEAR, FLUSHALL, PING, INFO, STATS, CONFIG, QUIT")
 	fmt.Println("Type 'help' for more information")
 
 	scanner := bufio.NewScanner(os.Stdin)
@@ -213,10 +1090,23 @@ func (c *Client) InteractiveMode() {
 		// Pretty print response
 		if strings.HasPrefix(response, "+") {
 			fmt.Printf("OK: %s\n", response[1:])
-		} else if strings.HasPrefix(response, "-ERR") {
-			fmt.Printf("ERROR: %s\n", response[5:])
+		} else if strings.HasPrefix(response, "-") {
+			fmt.Printf("ERROR: %v\n", replyError(response))
+		} else if strings.HasPrefix(response, "*") {
+			elements, ok, _ := parseArray(response)
+			if len(elements) == 0 {
+				fmt.Println("(empty)")
+			}
+			for i, element := range elements {
+				if !ok[i] {
+					element = "(nil)"
+				}
+				fmt.Printf("%d) %s\n", i+1, element)
+			}
 		} else if strings.HasPrefix(response, ":") {
 			fmt.Printf("VALUE: %s\n", response[1:])
+		} else if response == "$-1" {
+			fmt.Println("(nil)")
 		} else {
 			fmt.Printf("RESPONSE: %s\n", response)
 		}
@@ -3,33 +3,68 @@ package cache
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net"
 	"os"
 	"os/signal"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
 
 type Server struct {
-	cache    *LRUCache
-	listener net.Listener
-	address  string
-	ctx      context.Context
-	cancel   context.CancelFunc
+	config     ServerConfig
+	mu         sync.Mutex // guards namespaces
+	namespaces map[string]*namespace
+	listener   net.Listener
+	address    string
+	ctx        context.Context
+	cancel     context.CancelFunc
+}
+
+// ServerConfig holds the settings of a cache server
+type ServerConfig struct {
+	Address   string
+	Capacity  int    // max number of keys, 0 for no limit
+	MaxMemory int64  // max bytes used by keys and values, 0 for no limit
+	Policy    string // eviction policy, one of Policies, defaults to LRU
+	Admission bool   // enable the TinyLFU admission filter
+
+	// MaxPinnedShare is the share of a namespace pinned keys may use,
+	// DefaultMaxPinnedShare if 0
+	MaxPinnedShare float64
+
+	// Namespaces sizes namespaces that differ from Capacity and MaxMemory.
+	// Other namespaces are created on first SELECT, up to MaxNamespaces
+	// (DefaultMaxNamespaces if 0) including DefaultNamespace.
+	Namespaces    map[string]NamespaceConfig
+	MaxNamespaces int
 }
 
 func NewServer(address string, cacheCapacity int) *Server {
+	return NewServerWithConfig(ServerConfig{Address: address, Capacity: cacheCapacity})
+}
+
+func NewServerWithConfig(config ServerConfig) *Server {
 	ctx, cancel := context.WithCancel(context.Background())
 
-	return &Server{
-		cache:   NewLRUCache(cacheCapacity),
-		address: address,
-		ctx:     ctx,
-		cancel:  cancel,
+	s := &Server{
+		config:     config,
+		namespaces: make(map[string]*namespace),
+		address:    config.Address,
+		ctx:        ctx,
+		cancel:     cancel,
 	}
+	if _, err := s.openNamespace(DefaultNamespace); err != nil {
+		panic(err)
+	}
+	return s
 }
 
 func (s *Server) Start() error {
@@ -40,7 +75,10 @@ func (s *Server) Start() error {
 	}
 
 	log.Printf("GCache server started on %s", s.address)
-	log.Printf("Cache capacity: %d", s.cache.capacity)
+	ns, _ := s.openNamespace(DefaultNamespace)
+	log.Printf("Cache capacity: %d", ns.cache.Capacity())
+	log.Printf("Cache max memory: %d bytes", ns.cache.MaxBytes())
+	log.Printf("Eviction policy: %s", ns.cache.Policy())
 
 	// Handle graceful shutdown
 	go s.handleShutdown()
@@ -76,6 +114,9 @@ func (s *Server) handleConnection(conn net.Conn) {
 	scanner := bufio.NewScanner(conn)
 	writer := bufio.NewWriter(conn)
 
+	ns, _ := s.openNamespace(DefaultNamespace)
+	sess := &session{Server: s, namespace: ns}
+
 	for scanner.Scan() {
 		select {
 		case <-s.ctx.Done():
@@ -86,7 +127,7 @@ func (s *Server) handleConnection(conn net.Conn) {
 				continue
 			}
 
-			response := s.processCommand(line)
+			response := sess.processCommand(line)
 
 			if _, err := writer.WriteString(response + "\r\n"); err != nil {
 				log.Printf("Error writing to client %s: %v", clientAddr, err)
@@ -97,6 +138,11 @@ func (s *Server) handleConnection(conn net.Conn) {
 				log.Printf("Error flushing to client %s: %v", clientAddr, err)
 				return
 			}
+
+			// a blocking command was released by Stop
+			if s.ctx.Err() != nil {
+				return
+			}
 		}
 	}
 
@@ -105,7 +151,7 @@ func (s *Server) handleConnection(conn net.Conn) {
 	}
 }
 
-func (s *Server) processCommand(command string) string {
+func (s *session) processCommand(command string) string {
 	parts := strings.Fields(command)
 	if len(parts) == 0 {
 		return "-ERR empty command"
@@ -118,8 +164,134 @@ func (s *Server) processCommand(command string) string {
 		return s.handleGet(parts)
 	case "SET":
 		return s.handleSet(parts)
+	case "SETTAGGED":
+		return s.handleSetTagged(parts)
+	case "PIN":
+		return s.handlePin(parts)
+	case "UNPIN":
+		return s.handleUnpin(parts)
+	case "INVALIDATE":
+		return s.handleInvalidate(parts)
 	case "DEL":
 		return s.handleDel(parts)
+	case "GETS":
+		return s.handleGets(parts)
+	case "CAS":
+		return s.handleCAS(parts)
+	case "INCR":
+		return s.handleIncr(parts, 1)
+	case "DECR":
+		return s.handleIncr(parts, -1)
+	case "INCRBY":
+		return s.handleIncrBy(parts, 1)
+	case "DECRBY":
+		return s.handleIncrBy(parts, -1)
+	case "INCRBYFLOAT":
+		return s.handleIncrByFloat(parts)
+	case "HSET":
+		return s.handleHSet(parts)
+	case "HGET":
+		return s.handleHGet(parts)
+	case "HMGET":
+		return s.handleHMGet(parts)
+	case "HGETALL":
+		return s.handleHGetAll(parts)
+	case "HDEL":
+		return s.handleHDel(parts)
+	case "HLEN":
+		return s.handleHLen(parts)
+	case "HEXISTS":
+		return s.handleHExists(parts)
+	case "HINCRBY":
+		return s.handleHIncrBy(parts)
+	case "LPUSH":
+		return s.handlePush(parts, false)
+	case "RPUSH":
+		return s.handlePush(parts, true)
+	case "LPOP":
+		return s.handlePop(parts, false)
+	case "RPOP":
+		return s.handlePop(parts, true)
+	case "BLPOP":
+		return s.handleBlockingPop(parts, false)
+	case "BRPOP":
+		return s.handleBlockingPop(parts, true)
+	case "LRANGE":
+		return s.handleLRange(parts)
+	case "LLEN":
+		return s.handleLLen(parts)
+	case "LTRIM":
+		return s.handleLTrim(parts)
+	case "SADD":
+		return s.handleSAdd(parts)
+	case "SREM":
+		return s.handleSRem(parts)
+	case "SISMEMBER":
+		return s.handleSIsMember(parts)
+	case "SMEMBERS":
+		return s.handleSMembers(parts)
+	case "SCARD":
+		return s.handleSCard(parts)
+	case "SPOP":
+		return s.handleSPop(parts)
+	case "SRANDMEMBER":
+		return s.handleSRandMember(parts)
+	case "SINTER":
+		return s.handleSetOp(parts, setInter, false)
+	case "SUNION":
+		return s.handleSetOp(parts, setUnion, false)
+	case "SDIFF":
+		return s.handleSetOp(parts, setDiff, false)
+	case "SINTERSTORE":
+		return s.handleSetOp(parts, setInter, true)
+	case "SUNIONSTORE":
+		return s.handleSetOp(parts, setUnion, true)
+	case "SDIFFSTORE":
+		return s.handleSetOp(parts, setDiff, true)
+	case "ZADD":
+		return s.handleZAdd(parts)
+	case "ZREM":
+		return s.handleZRem(parts)
+	case "ZSCORE":
+		return s.handleZScore(parts)
+	case "ZINCRBY":
+		return s.handleZIncrBy(parts)
+	case "ZRANK":
+		return s.handleZRank(parts)
+	case "ZRANGE":
+		return s.handleZRange(parts, false)
+	case "ZREVRANGE":
+		return s.handleZRange(parts, true)
+	case "ZRANGEBYSCORE":
+		return s.handleZRangeByScore(parts)
+	case "ZCARD":
+		return s.handleZCard(parts)
+	case "TYPE":
+		return s.handleType(parts)
+	case "OBJECT":
+		return s.handleObject(parts)
+	case "MEMORY":
+		return s.handleMemory(parts)
+	case "DEBUG":
+		return s.handleDebug(parts)
+	case "KEYS":
+		return s.handleKeys(parts)
+	case "SCAN":
+		return s.handleScan(parts)
+	case "EXPIRE":
+		return s.handleExpire(parts, time.Second)
+	case "PEXPIRE":
+		return s.handleExpire(parts, time.Millisecond)
+	case "TTL":
+		return s.handleTTL(parts, time.Second)
+	case "PTTL":
+		return s.handleTTL(parts, time.Millisecond)
+	case "PERSIST":
+		return s.handlePersist(parts)
+	case "SELECT":
+		return s.handleSelect(parts)
+	case "FLUSHALL":
+		return s.handleFlushAll(parts)
 	case "SIZE":
 		return s.handleSize(parts)
 	case "CLEAR":
@@ -130,6 +302,8 @@ func (s *Server) processCommand(command string) string {
 		return s.handleInfo(parts)
 	case "STATS":
 		return s.handleStats(parts)
+	case "CONFIG":
+		return s.handleConfig(parts)
 	case "QUIT":
 		return s.handleQuit(parts)
 	default:
@@ -137,32 +311,235 @@ func (s *Server) processCommand(command string) string {
 	}
 }
 
-func (s *Server) handleGet(parts []string) string {
+// nilElement is an array element for a missing value
+const nilElement = "$-1"
+
+// arrayReply sends several replies as one: a '*' line with their count,
+// then one line per element
+func arrayReply(elements []string) string {
+	return fmt.Sprintf("*%d", len(elements)) + strings.Join(append([]string{""}, elements...), "\r\n")
+}
+
+// errorReply formats an error, WRONGTYPE errors keep their own prefix
+func errorReply(err error) string {
+	if errors.Is(err, ErrWrongType) {
+		return "-" + err.Error()
+	}
+	return fmt.Sprintf("-ERR %v", err)
+}
+
+func (s *session) handleGet(parts []string) string {
 	if len(parts) != 2 {
 		return "-ERR wrong number of arguments for 'GET' command"
 	}
 
 	key := parts[1]
-	if value, exists := s.cache.Get(key); exists {
-		return fmt.Sprintf("+%s", value)
+	value, exists := s.cache.Get(key)
+	if !exists {
+		return "-ERR key not found"
 	}
-	return "-ERR key not found"
+	if str, ok := value.(StringValue); ok {
+		return fmt.Sprintf("+%s", str)
+	}
+	return errorReply(ErrWrongType)
 }
 
-func (s *Server) handleSet(parts []string) string {
+func (s *session) handleSet(parts []string) string {
 	if len(parts) < 3 {
 		return "-ERR wrong number of arguments for 'SET' command"
 	}
 
 	key := parts[1]
+	valueParts, options, err := parseSetOptions(parts[2:])
+	if err != nil {
+		return fmt.Sprintf("-ERR %v", err)
+	}
+
+	// like Redis, a key set to expire in the past expires right away
+	if options.expired {
+		s.cache.Delete(key)
+		return "+OK"
+	}
+
 	// Join remaining parts as value (allows spaces in values)
-	value := strings.Join(parts[2:], " ")
+	value := strings.Join(valueParts, " ")
+
+	if options.pin {
+		err = s.cache.PutPinned(key, StringValue(value), options.ttl)
+	} else {
+		err = s.cache.PutWithTTL(key, StringValue(value), options.ttl)
+	}
+	if err != nil {
+		return fmt.Sprintf("-ERR %v", err)
+	}
+	return "+OK"
+}
+
+// handleSetTagged is SET with tags: SETTAGGED key value [EX s|...] TAGS tag [tag ...].
+// The value ends at the first TAGS word.
+func (s *session) handleSetTagged(parts []string) string {
+	tagsAt := -1
+	if len(parts) > 3 {
+		if i := slices.IndexFunc(parts[3:], func(part string) bool { return strings.ToUpper(part) == "TAGS" }); i >= 0 {
+			tagsAt = i + 3
+		}
+	}
+	if tagsAt < 0 || tagsAt == len(parts)-1 {
+		return "-ERR wrong number of arguments for 'SETTAGGED' command"
+	}
+
+	valueParts, options, err := parseSetOptions(parts[2:tagsAt])
+	if err != nil {
+		return fmt.Sprintf("-ERR %v", err)
+	}
+	if options.pin {
+		return "-ERR PIN is not supported by 'SETTAGGED'"
+	}
+	if options.expired {
+		s.cache.Delete(parts[1])
+		return "+OK"
+	}
 
-	s.cache.Put(key, value)
+	value := strings.Join(valueParts, " ")
+	if err := s.cache.PutTagged(parts[1], StringValue(value), options.ttl, parts[tagsAt+1:]...); err != nil {
+		return fmt.Sprintf("-ERR %v", err)
+	}
 	return "+OK"
 }
 
-func (s *Server) handleDel(parts []string) string {
+// handleInvalidate removes the keys with a tag and replies with their number
+func (s *session) handleInvalidate(parts []string) string {
+	if len(parts) != 2 {
+		return "-ERR wrong number of arguments for 'INVALIDATE' command"
+	}
+
+	return fmt.Sprintf(":%d", s.cache.InvalidateTag(parts[1]))
+}
+
+// handlePin keeps a key from being evicted, replying :0 if it is missing
+func (s *session) handlePin(parts []string) string {
+	if len(parts) != 2 {
+		return "-ERR wrong number of arguments for 'PIN' command"
+	}
+
+	switch err := s.cache.Pin(parts[1]); {
+	case errors.Is(err, ErrNotFound):
+		return ":0"
+	case err != nil:
+		return errorReply(err)
+	}
+	return ":1"
+}
+
+// handleUnpin replies :1 if the key was pinned
+func (s *session) handleUnpin(parts []string) string {
+	if len(parts) != 2 {
+		return "-ERR wrong number of arguments for 'UNPIN' command"
+	}
+
+	if s.cache.Unpin(parts[1]) {
+		return ":1"
+	}
+	return ":0"
+}
+
+// setOptions are the options of SET after the value
+type setOptions struct {
+	ttl     time.Duration // 0 if none
+	expired bool          // EXAT or PXAT in the past, the key is deleted
+	pin     bool
+}
+
+// parseSetOptions strips the trailing EX/PX/EXAT/PXAT and PIN options, in any
+// order, from the SET arguments and returns the value parts and the options.
+// As values may have spaces, options are only recognized in upper case and
+// each at most once, so values such as "deus ex machina", "run ex 5" or
+// "enter your pin" are stored as they are. The first word is always value.
+func parseSetOptions(args []string) ([]string, setOptions, error) {
+	var options setOptions
+	hasTTL := false
+	for len(args) > 1 {
+		if args[len(args)-1] == "PIN" && !options.pin {
+			options.pin = true
+			args = args[:len(args)-1]
+			continue
+		}
+		if len(args) < 3 || hasTTL {
+			break
+		}
+
+		option := args[len(args)-2]
+		switch option {
+		case "EX", "PX", "EXAT", "PXAT":
+		default:
+			return args, options, nil
+		}
+
+		n, err := strconv.ParseInt(args[len(args)-1], 10, 64)
+		if err != nil || n <= 0 {
+			return nil, options, fmt.Errorf("invalid expire time in 'set' command")
+		}
+
+		ok := true
+		switch option {
+		case "EX":
+			options.ttl, ok = expireDuration(n, time.Second)
+		case "PX":
+			options.ttl, ok = expireDuration(n, time.Millisecond)
+		case "EXAT":
+			options.ttl = time.Until(time.Unix(n, 0))
+			options.expired = options.ttl <= 0
+		case "PXAT":
+			options.ttl = time.Until(time.UnixMilli(n))
+			options.expired = options.ttl <= 0
+		}
+
+		if !ok || (options.ttl <= 0 && !options.expired) {
+			return nil, options, fmt.Errorf("invalid expire time in 'set' command")
+		}
+		hasTTL = true
+		args = args[:len(args)-2]
+	}
+	return args, options, nil
+}
+
+// handleGets replies with the version and the value, separated by a space
+func (s *session) handleGets(parts []string) string {
+	if len(parts) != 2 {
+		return "-ERR wrong number of arguments for 'GETS' command"
+	}
+
+	value, version, exists := s.cache.GetWithVersion(parts[1])
+	if !exists {
+		return "-ERR key not found"
+	}
+	if str, ok := value.(StringValue); ok {
+		return fmt.Sprintf("+%d %s", version, str)
+	}
+	return errorReply(ErrWrongType)
+}
+
+// handleCAS sets the value if the key still has the given version and replies
+// with the new version
+func (s *session) handleCAS(parts []string) string {
+	if len(parts) < 4 {
+		return "-ERR wrong number of arguments for 'CAS' command"
+	}
+
+	version, err := strconv.ParseUint(parts[2], 10, 64)
+	if err != nil {
+		return "-ERR invalid version"
+	}
+	value := strings.Join(parts[3:], " ")
+
+	newVersion, err := s.cache.CompareAndSwap(parts[1], version, StringValue(value))
+	if err != nil {
+		return fmt.Sprintf("-ERR %v", err)
+	}
+	return fmt.Sprintf(":%d", newVersion)
+}
+
+func (s *session) handleDel(parts []string) string {
 	if len(parts) != 2 {
 		return "-ERR wrong number of arguments for 'DEL' command"
 	}
@@ -174,7 +551,273 @@ func (s *Server) handleDel(parts []string) string {
 	return "-ERR key not found"
 }
 
-func (s *Server) handleSize(parts []string) string {
+// handleIncr handles INCR and DECR, which add delta
+func (s *session) handleIncr(parts []string, delta int64) string {
+	if len(parts) != 2 {
+		return fmt.Sprintf("-ERR wrong number of arguments for '%s' command", strings.ToUpper(parts[0]))
+	}
+
+	var n int64
+	err := s.updateString(parts[1], func(value string, exists bool) (string, error) {
+		var err error
+		value, n, err = addInt(value, exists, delta)
+		return value, err
+	})
+	if err != nil {
+		return errorReply(err)
+	}
+	return fmt.Sprintf(":%d", n)
+}
+
+// handleIncrBy handles INCRBY and DECRBY, sign is -1 for DECRBY
+func (s *session) handleIncrBy(parts []string, sign int64) string {
+	if len(parts) != 3 {
+		return fmt.Sprintf("-ERR wrong number of arguments for '%s' command", strings.ToUpper(parts[0]))
+	}
+
+	delta, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil || (sign < 0 && delta == math.MinInt64) {
+		return "-ERR value is not an integer"
+	}
+	return s.handleIncr(parts[:2], sign*delta)
+}
+
+// handleIncrByFloat replies with the result as a '+' string, since it may not be an integer
+func (s *session) handleIncrByFloat(parts []string) string {
+	if len(parts) != 3 {
+		return "-ERR wrong number of arguments for 'INCRBYFLOAT' command"
+	}
+
+	delta, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil || math.IsNaN(delta) || math.IsInf(delta, 0) {
+		return "-ERR value is not a valid float"
+	}
+
+	var result string
+	err = s.updateString(parts[1], func(value string, exists bool) (string, error) {
+		var err error
+		result, _, err = addFloat(value, exists, delta)
+		return result, err
+	})
+	if err != nil {
+		return errorReply(err)
+	}
+	return fmt.Sprintf("+%s", result)
+}
+
+// updateString is Update for string values, failing with ErrWrongType for other types
+func (s *session) updateString(key string, fn func(value string, exists bool) (string, error)) error {
+	_, err := s.cache.Update(key, func(value Value, exists bool) (Value, error) {
+		str, ok := value.(StringValue)
+		if exists && !ok {
+			return value, ErrWrongType
+		}
+
+		updated, err := fn(string(str), exists)
+		return StringValue(updated), err
+	})
+	return err
+}
+
+func (s *session) handleType(parts []string) string {
+	if len(parts) != 2 {
+		return "-ERR wrong number of arguments for 'TYPE' command"
+	}
+
+	if value, exists := s.cache.Peek(parts[1]); exists {
+		return fmt.Sprintf("+%s", value.Type())
+	}
+	return "+none"
+}
+
+// handleObject reports how a key is used: OBJECT IDLETIME key replies with
+// the seconds since it was last read or written, OBJECT FREQ key with the
+// number of reads and writes
+func (s *session) handleObject(parts []string) string {
+	if len(parts) < 2 {
+		return "-ERR wrong number of arguments for 'OBJECT' command"
+	}
+
+	subcommand := strings.ToUpper(parts[1])
+	if subcommand != "IDLETIME" && subcommand != "FREQ" {
+		return fmt.Sprintf("-ERR unknown OBJECT subcommand '%s'", parts[1])
+	}
+	if len(parts) != 3 {
+		return "-ERR wrong number of arguments for 'OBJECT' command"
+	}
+
+	info, exists := s.cache.Inspect(parts[2])
+	switch {
+	case !exists:
+		return "-ERR key not found"
+	case subcommand == "IDLETIME":
+		return fmt.Sprintf(":%d", int64(info.Idle().Seconds()))
+	}
+	return fmt.Sprintf(":%d", info.AccessCount)
+}
+
+// handleMemory handles MEMORY USAGE key, replying with the approximate bytes
+// used by the key and its value
+func (s *session) handleMemory(parts []string) string {
+	if len(parts) < 2 {
+		return "-ERR wrong number of arguments for 'MEMORY' command"
+	}
+	if strings.ToUpper(parts[1]) != "USAGE" {
+		return fmt.Sprintf("-ERR unknown MEMORY subcommand '%s'", parts[1])
+	}
+	if len(parts) != 3 {
+		return "-ERR wrong number of arguments for 'MEMORY' command"
+	}
+
+	info, exists := s.cache.Inspect(parts[2])
+	if !exists {
+		return "-ERR key not found"
+	}
+	return fmt.Sprintf(":%d", info.Size)
+}
+
+// handleDebug handles DEBUG LRU n, replying with the n coldest keys in the
+// order they would be evicted
+func (s *session) handleDebug(parts []string) string {
+	if len(parts) < 2 {
+		return "-ERR wrong number of arguments for 'DEBUG' command"
+	}
+	if strings.ToUpper(parts[1]) != "LRU" {
+		return fmt.Sprintf("-ERR unknown DEBUG subcommand '%s'", parts[1])
+	}
+	if len(parts) != 3 {
+		return "-ERR wrong number of arguments for 'DEBUG' command"
+	}
+
+	n, err := strconv.Atoi(parts[2])
+	if err != nil || n < 0 {
+		return "-ERR value is out of range, must be positive"
+	}
+
+	keys := s.cache.Coldest(n)
+	elements := make([]string, len(keys))
+	for i, key := range keys {
+		elements[i] = "+" + key
+	}
+	return arrayReply(elements)
+}
+
+// handleKeys replies with all keys matching a pattern, sorted. It copies the
+// whole keyspace, so SCAN is better for large caches.
+func (s *session) handleKeys(parts []string) string {
+	if len(parts) != 2 {
+		return "-ERR wrong number of arguments for 'KEYS' command"
+	}
+
+	var elements []string
+	for _, key := range s.cache.Keys() {
+		if globMatch(parts[1], key) {
+			elements = append(elements, "+"+key)
+		}
+	}
+	slices.Sort(elements)
+	return arrayReply(elements)
+}
+
+// handleScan handles SCAN cursor [MATCH pattern] [COUNT n]. The first element
+// of the reply is the next cursor, 0 when the scan is over, and the others are
+// the matching keys among the next n examined.
+func (s *session) handleScan(parts []string) string {
+	if len(parts) < 2 {
+		return "-ERR wrong number of arguments for 'SCAN' command"
+	}
+
+	cursor, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil {
+		return "-ERR invalid cursor"
+	}
+
+	pattern, count := "*", DefaultScanCount
+	for i := 2; i < len(parts); i += 2 {
+		if i+1 == len(parts) {
+			return "-ERR syntax error"
+		}
+		switch strings.ToUpper(parts[i]) {
+		case "MATCH":
+			pattern = parts[i+1]
+		case "COUNT":
+			count, err = strconv.Atoi(parts[i+1])
+			if err != nil || count <= 0 {
+				return "-ERR value is not an integer or out of range"
+			}
+		default:
+			return "-ERR syntax error"
+		}
+	}
+
+	keys, next := s.cache.Scan(cursor, count)
+	elements := []string{fmt.Sprintf("+%d", next)}
+	for _, key := range keys {
+		if globMatch(pattern, key) {
+			elements = append(elements, "+"+key)
+		}
+	}
+	return arrayReply(elements)
+}
+
+func (s *session) handleExpire(parts []string, unit time.Duration) string {
+	if len(parts) != 3 {
+		return fmt.Sprintf("-ERR wrong number of arguments for '%s' command", strings.ToUpper(parts[0]))
+	}
+
+	n, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "-ERR value is not an integer or out of range"
+	}
+	ttl, ok := expireDuration(n, unit)
+	if !ok {
+		return fmt.Sprintf("-ERR invalid expire time in '%s' command", strings.ToLower(parts[0]))
+	}
+
+	if s.cache.Expire(parts[1], ttl) {
+		return ":1"
+	}
+	return ":0"
+}
+
+// expireDuration returns n units as a duration, false if it overflows
+func expireDuration(n int64, unit time.Duration) (time.Duration, bool) {
+	if n > math.MaxInt64/int64(unit) || n < math.MinInt64/int64(unit) {
+		return 0, false
+	}
+	return time.Duration(n) * unit, true
+}
+
+// handleTTL replies -2 if the key does not exist and -1 if it has no expiry
+func (s *session) handleTTL(parts []string, unit time.Duration) string {
+	if len(parts) != 2 {
+		return fmt.Sprintf("-ERR wrong number of arguments for '%s' command", strings.ToUpper(parts[0]))
+	}
+
+	ttl, exists := s.cache.TTL(parts[1])
+	if !exists {
+		return ":-2"
+	}
+	if ttl == NoExpiration {
+		return ":-1"
+	}
+
+	// round to the nearest unit like Redis does
+	return fmt.Sprintf(":%d", (ttl+unit/2)/unit)
+}
+
+func (s *session) handlePersist(parts []string) string {
+	if len(parts) != 2 {
+		return "-ERR wrong number of arguments for 'PERSIST' command"
+	}
+
+	if s.cache.Persist(parts[1]) {
+		return ":1"
+	}
+	return ":0"
+}
+
+func (s *session) handleSize(parts []string) string {
 	if len(parts) != 1 {
 		return "-ERR wrong number of arguments for 'SIZE' command"
 	}
@@ -182,7 +825,7 @@ func (s *Server) handleSize(parts []string) string {
 	return fmt.Sprintf(":%d", s.cache.Size())
 }
 
-func (s *Server) handleClear(parts []string) string {
+func (s *session) handleClear(parts []string) string {
 	if len(parts) != 1 {
 		return "-ERR wrong number of arguments for 'CLEAR' command"
 	}
@@ -200,32 +843,86 @@ func (s *Server) handlePing(parts []string) string {
 	return "-ERR wrong number of arguments for 'PING' command"
 }
 
-func (s *Server) handleInfo(parts []string) string {
+func (s *session) handleInfo(parts []string) string {
 	if len(parts) != 1 {
 		return "-ERR wrong number of arguments for 'INFO' command"
 	}
 
 	// Single line info to avoid parsing issues
-	info := fmt.Sprintf("gcache_version:1.0 cache_capacity:%d cache_size:%d uptime_seconds:%.0f",
-		s.cache.capacity,
+	info := fmt.Sprintf("gcache_version:1.0 namespace:%s cache_capacity:%d cache_size:%d used_memory:%d maxmemory:%d eviction_policy:%s uptime_seconds:%.0f",
+		s.name,
+		s.cache.Capacity(),
 		s.cache.Size(),
+		s.cache.UsedBytes(),
+		s.cache.MaxBytes(),
+		s.cache.Policy(),
 		time.Since(startTime).Seconds())
 
-	return fmt.Sprintf("+%s", info)
+	return fmt.Sprintf("+%s %s", info, s.namespaceInfo())
 }
 
-func (s *Server) handleStats(parts []string) string {
+func (s *session) handleStats(parts []string) string {
+	if len(parts) == 2 && strings.ToUpper(parts[1]) == "RESET" {
+		s.cache.ResetStats()
+		return "+OK"
+	}
 	if len(parts) != 1 {
 		return "-ERR wrong number of arguments for 'STATS' command"
 	}
 
 	// Single line stats to avoid multi-line parsing issues
-	stats := fmt.Sprintf("size:%d capacity:%d",
-		s.cache.Size(), s.cache.capacity)
+	st := s.cache.Stats()
+	pinned, _ := s.cache.Pinned()
+	stats := fmt.Sprintf("size:%d capacity:%d used_memory:%d maxmemory:%d "+
+		"hits:%d misses:%d hit_ratio:%.4f sets:%d deletes:%d evictions:%d expirations:%d "+
+		"admission_accepted:%d admission_rejected:%d stale_hits:%d refreshes:%d refresh_failures:%d "+
+		"pinned:%d pinned_share:%.4f",
+		s.cache.Size(), s.cache.Capacity(), s.cache.UsedBytes(), s.cache.MaxBytes(),
+		st.Hits, st.Misses, st.HitRatio(), st.Sets, st.Deletes, st.Evictions, st.Expirations,
+		st.AdmissionAccepted, st.AdmissionRejected, st.StaleHits, st.Refreshes, st.RefreshFailures,
+		pinned, s.cache.PinnedShare())
 
 	return fmt.Sprintf("+%s", stats)
 }
 
+// handleConfig reads settings with CONFIG GET and changes the ones that can
+// change at runtime with CONFIG SET
+func (s *session) handleConfig(parts []string) string {
+	if len(parts) < 3 {
+		return "-ERR wrong number of arguments for 'CONFIG' command"
+	}
+
+	subcommand, parameter := strings.ToUpper(parts[1]), strings.ToLower(parts[2])
+	switch {
+	case subcommand == "GET" && len(parts) == 3:
+		switch parameter {
+		case "capacity":
+			return fmt.Sprintf(":%d", s.cache.Capacity())
+		case "maxmemory":
+			return fmt.Sprintf(":%d", s.cache.MaxBytes())
+		case "eviction_policy":
+			return fmt.Sprintf("+%s", s.cache.Policy())
+		}
+	case subcommand == "SET" && len(parts) == 4:
+		switch parameter {
+		case "capacity":
+			capacity, err := strconv.Atoi(parts[3])
+			if err != nil {
+				return "-ERR value is not an integer"
+			}
+			if err := s.cache.Resize(capacity); err != nil {
+				return fmt.Sprintf("-ERR %v", err)
+			}
+			return "+OK"
+		}
+	case subcommand != "GET" && subcommand != "SET":
+		return fmt.Sprintf("-ERR unknown CONFIG subcommand '%s'", parts[1])
+	default:
+		return "-ERR wrong number of arguments for 'CONFIG' command"
+	}
+	return fmt.Sprintf("-ERR unsupported CONFIG parameter '%s'", parts[2])
+}
+
 func (s *Server) handleQuit(parts []string) string {
 	return "+BYE"
 }
@@ -245,6 +942,9 @@ func (s *Server) Stop() {
 	if s.listener != nil {
 		s.listener.Close()
 	}
+	for _, ns := range s.allNamespaces() {
+		ns.cache.Close()
+	}
 	log.Println("Server stopped")
 }
 
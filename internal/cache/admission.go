@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"hash/maphash"
+	"math/bits"
+)
+
+const (
+	sketchDepth      = 4
+	sketchMaxCount   = 15 // 4-bit counters, as in the TinyLFU paper
+	doorkeeperProbes = 3
+
+	// sketch size used when the cache is bounded by memory only
+	defaultAdmissionSize = 10000
+	minAdmissionSize     = 64
+)
+
+// tinyLFU is an admission filter in front of the eviction policy: a new key
+// only displaces the victim if it has been requested more often recently.
+// Frequencies come from a count-min sketch that is halved every sampleSize
+// records, so old popularity fades out. A doorkeeper bloom filter absorbs the
+// first request of each key so one-off keys never reach the sketch.
+type tinyLFU[K comparable] struct {
+	seed       maphash.Seed
+	sketch     []uint8 // sketchDepth rows of width counters
+	width      uint64  // power of two
+	doorkeeper []uint64
+	samples    int
+	sampleSize int
+
+	// key of the last Get that missed, see recordWrite
+	lastMiss    K
+	hasLastMiss bool
+}
+
+func newTinyLFU[K comparable](capacity int) *tinyLFU[K] {
+	if capacity <= 0 {
+		capacity = defaultAdmissionSize
+	}
+	// tiny sketches collide too much to tell keys apart
+	capacity = max(capacity, minAdmissionSize)
+
+	sampleSize := 10 * capacity
+	width := nextPowerOfTwo(uint64(capacity))
+	doorkeeperBits := max(nextPowerOfTwo(uint64(sampleSize)*4), 64) // ~4 bits per sampled key
+
+	return &tinyLFU[K]{
+		seed:       maphash.MakeSeed(),
+		sketch:     make([]uint8, sketchDepth*width),
+		width:      width,
+		doorkeeper: make([]uint64, doorkeeperBits/64),
+		sampleSize: sampleSize,
+	}
+}
+
+// index derives the i-th hash from two halves of one 64-bit hash
+func (t *tinyLFU[K]) index(hash uint64, i int, size uint64) uint64 {
+	h1, h2 := hash, (hash>>32)|1
+	return (h1 + uint64(i)*h2) & (size - 1)
+}
+
+// recordRead counts a Get of key
+func (t *tinyLFU[K]) recordRead(key K, hit bool) {
+	t.record(key)
+	t.lastMiss, t.hasLastMiss = key, !hit
+}
+
+// recordWrite counts a Put of key. The usual read-through pattern of a missed
+// Get followed by a Put of the same key is a single request and counts once.
+func (t *tinyLFU[K]) recordWrite(key K) {
+	if t.hasLastMiss && t.lastMiss == key {
+		t.hasLastMiss = false
+		return
+	}
+	t.record(key)
+}
+
+// record counts one request of key
+func (t *tinyLFU[K]) record(key K) {
+	hash := maphash.Comparable(t.seed, key)
+
+	if t.doorkeeperAdd(hash) {
+		for i := 0; i < sketchDepth; i++ {
+			idx := uint64(i)*t.width + t.index(hash, i, t.width)
+			if t.sketch[idx] < sketchMaxCount {
+				t.sketch[idx]++
+			}
+		}
+	}
+
+	t.samples++
+	if t.samples >= t.sampleSize {
+		t.age()
+	}
+}
+
+func (t *tinyLFU[K]) estimate(key K) int {
+	hash := maphash.Comparable(t.seed, key)
+
+	count := uint8(sketchMaxCount)
+	for i := 0; i < sketchDepth; i++ {
+		count = min(count, t.sketch[uint64(i)*t.width+t.index(hash, i, t.width)])
+	}
+
+	if t.doorkeeperContains(hash) {
+		return int(count) + 1
+	}
+	return int(count)
+}
+
+// admit reports whether candidate should replace victim
+func (t *tinyLFU[K]) admit(candidate, victim K) bool {
+	return t.estimate(candidate) > t.estimate(victim)
+}
+
+// age halves all counters and clears the doorkeeper
+func (t *tinyLFU[K]) age() {
+	for i := range t.sketch {
+		t.sketch[i] >>= 1
+	}
+	clear(t.doorkeeper)
+	t.samples /= 2
+}
+
+// doorkeeperAdd sets the key bits and reports whether they were all set already
+func (t *tinyLFU[K]) doorkeeperAdd(hash uint64) bool {
+	size := uint64(len(t.doorkeeper)) * 64
+	seen := true
+	for i := 0; i < doorkeeperProbes; i++ {
+		bit := t.index(hash, i, size)
+		word, mask := bit/64, uint64(1)<<(bit%64)
+		if t.doorkeeper[word]&mask == 0 {
+			seen = false
+			t.doorkeeper[word] |= mask
+		}
+	}
+	return seen
+}
+
+func (t *tinyLFU[K]) doorkeeperContains(hash uint64) bool {
+	size := uint64(len(t.doorkeeper)) * 64
+	for i := 0; i < doorkeeperProbes; i++ {
+		bit := t.index(hash, i, size)
+		if t.doorkeeper[bit/64]&(uint64(1)<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func nextPowerOfTwo(n uint64) uint64 {
+	if n <= 1 {
+		return 1
+	}
+	return uint64(1) << bits.Len64(n-1)
+}
@@ -0,0 +1,120 @@
+package cache
+
+const (
+	queueT1 uint8 = iota + 1 // ARC: entries seen once recently
+	queueT2                  // ARC: entries seen at least twice recently
+)
+
+// arcPolicy is the Adaptive Replacement Cache (Megiddo and Modha). It balances
+// recency (t1) against frequency (t2) and moves the target size p of t1 towards
+// whichever side the ghost lists show would have produced more hits.
+//
+// Unlike the paper, p is adapted when a returning key is inserted, which
+// happens right after the victim was chosen rather than right before.
+type arcPolicy[K comparable, V any] struct {
+	capacity int
+	p        int                     // target size of t1
+	t1       *DoublyLinkedList[K, V] // MRU at head
+	t2       *DoublyLinkedList[K, V] // MRU at head
+	b1       *ghostList[K]           // keys recently evicted from t1
+	b2       *ghostList[K]           // keys recently evicted from t2
+}
+
+func newARCPolicy[K comparable, V any](capacity int) *arcPolicy[K, V] {
+	return &arcPolicy[K, V]{
+		capacity: capacity,
+		t1:       NewDoublyLinkedList[K, V](),
+		t2:       NewDoublyLinkedList[K, V](),
+		b1:       newGhostList[K](),
+		b2:       newGhostList[K](),
+	}
+}
+
+func (p *arcPolicy[K, V]) Name() string { return PolicyARC }
+
+func (p *arcPolicy[K, V]) size() int {
+	if p.capacity > 0 {
+		return p.capacity
+	}
+	return max(p.t1.Count()+p.t2.Count(), 1)
+}
+
+func (p *arcPolicy[K, V]) OnInsert(node *DoublyNode[K, V]) {
+	b1Len, b2Len := p.b1.len(), p.b2.len()
+
+	switch {
+	case p.b1.remove(node.key):
+		// recency would have hit, grow t1
+		p.p = min(p.p+max(b2Len/b1Len, 1), p.size())
+		node.queue = queueT2
+		p.t2.InsertAtFront(node)
+	case p.b2.remove(node.key):
+		// frequency would have hit, shrink t1
+		p.p = max(p.p-max(b1Len/b2Len, 1), 0)
+		node.queue = queueT2
+		p.t2.InsertAtFront(node)
+	default:
+		node.queue = queueT1
+		p.t1.InsertAtFront(node)
+	}
+
+	p.trimGhosts()
+}
+
+func (p *arcPolicy[K, V]) OnAccess(node *DoublyNode[K, V]) {
+	p.queueOf(node).Remove(node)
+	node.queue = queueT2
+	p.t2.InsertAtFront(node)
+}
+
+func (p *arcPolicy[K, V]) OnRemove(node *DoublyNode[K, V]) {
+	p.queueOf(node).Remove(node)
+}
+
+func (p *arcPolicy[K, V]) OnEvict(node *DoublyNode[K, V]) {
+	p.queueOf(node).Remove(node)
+
+	if node.queue == queueT1 {
+		p.b1.add(node.key)
+	} else {
+		p.b2.add(node.key)
+	}
+	p.trimGhosts()
+}
+
+func (p *arcPolicy[K, V]) Victim() *DoublyNode[K, V] {
+	if p.t1.Count() > 0 && (p.t1.Count() > p.p || p.t2.Count() == 0) {
+		return p.t1.last
+	}
+	return p.t2.last
+}
+
+func (p *arcPolicy[K, V]) Walk(coldestFirst bool, fn func(node *DoublyNode[K, V]) bool) {
+	if coldestFirst {
+		walkLists(coldestFirst, fn, p.t1, p.t2)
+	} else {
+		walkLists(coldestFirst, fn, p.t2, p.t1)
+	}
+}
+
+func (p *arcPolicy[K, V]) Reset() {
+	p.p = 0
+	p.t1 = NewDoublyLinkedList[K, V]()
+	p.t2 = NewDoublyLinkedList[K, V]()
+	p.b1 = newGhostList[K]()
+	p.b2 = newGhostList[K]()
+}
+
+// trimGhosts keeps |t1|+|b1| <= c and |t1|+|t2|+|b1|+|b2| <= 2c
+func (p *arcPolicy[K, V]) trimGhosts() {
+	c := p.size()
+	p.b1.trim(max(c-p.t1.Count(), 0))
+	p.b2.trim(max(2*c-p.t1.Count()-p.t2.Count()-p.b1.len(), 0))
+}
+
+func (p *arcPolicy[K, V]) queueOf(node *DoublyNode[K, V]) *DoublyLinkedList[K, V] {
+	if node.queue == queueT2 {
+		return p.t2
+	}
+	return p.t1
+}
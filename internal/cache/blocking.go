@@ -0,0 +1,127 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	errTimeout       = errors.New("timeout")
+	errServerStopped = errors.New("server is shutting down")
+)
+
+// popFunc pops an element from the list at key, see Server.pop
+type popFunc func(key string, fromTail bool) (value string, ok bool, err error)
+
+// waiter is a client blocked in BLPOP or BRPOP
+type waiter struct {
+	keys     []string
+	fromTail bool
+	ready    chan poppedValue // buffered, receives the popped element
+	done     bool             // served or gone, guarded by waiters.mu
+}
+
+type poppedValue struct {
+	key   string
+	value string
+}
+
+// waiters holds the clients blocked on list keys. Each key has a FIFO queue,
+// so the client that has waited longest gets the next pushed element.
+type waiters struct {
+	mu    sync.Mutex
+	byKey map[string][]*waiter
+}
+
+func newWaiters() *waiters {
+	return &waiters{byKey: make(map[string][]*waiter)}
+}
+
+// pop pops from the first non-empty list of keys, or waits until a push
+// serves it, the timeout fires (0 waits forever) or ctx is done. The first
+// attempt and the registration happen under mu, and pushes serve waiters
+// under mu, so a push cannot slip in between unnoticed.
+func (ws *waiters) pop(ctx context.Context, keys []string, fromTail bool, timeout time.Duration, pop popFunc) (string, string, error) {
+	ws.mu.Lock()
+	for _, key := range keys {
+		value, ok, err := pop(key, fromTail)
+		if err != nil || ok {
+			ws.mu.Unlock()
+			return key, value, err
+		}
+	}
+
+	w := &waiter{keys: keys, fromTail: fromTail, ready: make(chan poppedValue, 1)}
+	for _, key := range keys {
+		ws.byKey[key] = append(ws.byKey[key], w)
+	}
+	ws.mu.Unlock()
+
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+
+	var err error
+	select {
+	case popped := <-w.ready:
+		return popped.key, popped.value, nil
+	case <-expired:
+		err = errTimeout
+	case <-ctx.Done():
+		err = errServerStopped
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	// served while giving up
+	if w.done {
+		popped := <-w.ready
+		return popped.key, popped.value, nil
+	}
+	ws.remove(w)
+	return "", "", err
+}
+
+// serve hands elements of the list at key to its waiters, oldest first,
+// until the list or the queue is empty
+func (ws *waiters) serve(key string, pop popFunc) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	for len(ws.byKey[key]) > 0 {
+		w := ws.byKey[key][0]
+		value, ok, err := pop(key, w.fromTail)
+		if err != nil || !ok {
+			return
+		}
+
+		w.ready <- poppedValue{key: key, value: value}
+		ws.remove(w)
+	}
+}
+
+// remove marks w done and takes it out of the queues of all its keys
+func (ws *waiters) remove(w *waiter) {
+	w.done = true
+	for _, key := range w.keys {
+		queue := ws.byKey[key]
+		for i, other := range queue {
+			if other == w {
+				queue = append(queue[:i], queue[i+1:]...)
+				break
+			}
+		}
+
+		if len(queue) == 0 {
+			delete(ws.byKey, key)
+		} else {
+			ws.byKey[key] = queue
+		}
+	}
+}
@@ -1,130 +1,698 @@
 package cache
 
 import (
+	"cmp"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 )
 
-type LRUCache struct {
-	capacity int
-	cache    map[string]*DoublyNode
-	list     *DoublyLinkedList
-	mu       sync.RWMutex
+const (
+	// how often the background sweeper looks for expired items, see
+	// Options.SweepInterval
+	DefaultSweepInterval = time.Second
+
+	// max expired items removed per lock acquisition by the sweeper
+	sweepBatchSize = 1000
+
+	// returned by TTL for keys without an expiration
+	NoExpiration time.Duration = -1
+)
+
+// ErrEntryTooLarge is returned when a single entry exceeds the memory budget
+var ErrEntryTooLarge = errors.New("entry exceeds cache max memory")
+
+// LRUCache maps keys of type K to values of type V, evicting entries once
+// capacity or the memory budget is reached. The least recently used entry is
+// evicted by default, Options.Policy selects another eviction policy.
+type LRUCache[K comparable, V any] struct {
+	capacity  int   // max number of items, 0 for no limit
+	maxBytes  int64 // max total weight of items, 0 for no limit
+	usedBytes int64
+	weigher   Weigher[K, V]
+
+	cache     map[K]*DoublyNode[K, V]
+	policy    EvictionPolicy[K, V]
+	admission *tinyLFU[K] // nil unless Options.Admission is set
+	expiries  expiryHeap[K, V]
+	stats     statsCounters
+	version   uint64                    // last version given to an entry
+	tags      map[string]map[K]struct{} // keys of each tag, see PutTagged
+	scanIndex scanIndex[K, V]
+	mu        sync.RWMutex
+
+	pinnedCount    int   // entries kept from eviction, see Pin
+	pinnedBytes    int64 // their total weight
+	maxPinnedShare float64
+
+	listeners []RemovalListener[K, V]
+	pending   []removal[K, V] // removals waiting to be delivered to listeners
+	notifying bool            // a goroutine is delivering pending removals
+
+	loads       map[K]*loadCall[V] // in-flight GetOrLoad loader calls
+	loadErrors  map[K]loadError    // failed loads, kept for negativeTTL
+	negativeTTL time.Duration
+	refresher   LoaderFunc[K, V] // reloads stale entries, see PutWithSoftTTL
+
+	store           Store[K, V]       // nil if the cache has no store
+	queue           *writeQueue[K, V] // nil unless in write-behind mode
+	writeMu         sync.Mutex        // orders write-through writes, see lockWrite
+	maxWriteRetries int
+	onStoreError    func(key K, err error)
+	flushed         chan struct{} // closed when the flusher is done
+
+	resizeMu      sync.Mutex // one Resize at a time
+	sweepInterval time.Duration
+	sweepOnce     sync.Once
+	closeOnce     sync.Once
+	stop          chan struct{}
 }
 
-type CacheItem struct {
-	key   string
-	value string
+type CacheItem[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time   // zero means the item never expires
+	heapIndex int         // position in expiries, -1 if not tracked
+	size      int64       // weight in bytes, counted against maxBytes
+	version   uint64      // bumped on every write of the value, see CompareAndSwap
+	tags      []string    // set by PutTagged, indexed in LRUCache.tags
+	refresh   *refreshTTL // set by PutWithSoftTTL
+	pinned    bool        // never evicted, see Pin
+	priority  Priority    // eviction class, see SetPriority
+
+	// access metadata, see Inspect
+	createdAt   time.Time
+	accessedAt  time.Time
+	accessCount uint64
 }
 
-func NewLRUCache(capacity int) *LRUCache {
+// Options configures a cache. At least one of Capacity and MaxBytes must be set,
+// when both are set an entry is evicted as soon as either limit is exceeded.
+type Options[K comparable, V any] struct {
+	Capacity int           // max number of items
+	MaxBytes int64         // max total weight of items in bytes
+	Weigher  Weigher[K, V] // entry weight, defaults to key and value length plus node overhead
+	Policy   string        // eviction policy, one of Policies, defaults to LRU
+
+	// Admission enables the TinyLFU admission filter: when the cache is full, a
+	// new key is only stored if it was requested more often than the victim
+	Admission bool
+
+	// MaxPinnedShare is the share of Capacity and of MaxBytes that pinned
+	// entries may use, DefaultMaxPinnedShare if 0. At most 1.
+	MaxPinnedShare float64
+
+	// NegativeTTL is how long GetOrLoad remembers a loader error, 0 to not remember it
+	NegativeTTL time.Duration
+
+	// SweepInterval is how often the background sweeper removes expired
+	// entries, DefaultSweepInterval if 0
+	SweepInterval time.Duration
+
+	// Refresher loads the new value of an entry stored with PutWithSoftTTL
+	// once it is stale. Without it, stale values are returned until they expire.
+	Refresher LoaderFunc[K, V]
+
+	// Store persists the entries behind the cache, written as set by WriteMode.
+	// Expiration times are not persisted, and evicted or expired entries stay
+	// in the store.
+	Store     Store[K, V]
+	WriteMode WriteMode
+
+	// write-behind settings, the Default* values are used when 0
+	FlushInterval   time.Duration // max time a write waits in the queue
+	FlushBatchSize  int           // writes per batch, a full batch is flushed right away
+	WriteQueueSize  int           // max queued keys, writers block while the queue is full
+	MaxWriteRetries int           // retries of a failed write before it is given up
+
+	// OnStoreError is called with writes that failed and are given up on: write-behind
+	// writes after MaxWriteRetries retries and write-through deletes
+	OnStoreError func(key K, err error)
+}
+
+func (item *CacheItem[K, V]) expired(now time.Time) bool {
+	return !item.expiresAt.IsZero() && !now.Before(item.expiresAt)
+}
+
+// NewLRUCache creates a string to string cache, as used by the server
+func NewLRUCache(capacity int) *LRUCache[string, string] {
+	return New[string, string](capacity)
+}
+
+func New[K comparable, V any](capacity int) *LRUCache[K, V] {
 	if capacity <= 0 {
 		panic("LRUCache capacity must be greater than 0")
 	}
 
-	return &LRUCache{
-		capacity: capacity,
-		cache:    make(map[string]*DoublyNode),
-		list:     NewDoublyLinkedList(),
-	}
+	return NewWithOptions(Options[K, V]{Capacity: capacity})
 }
 
-func (lru *LRUCache) Get(key string) (string, bool) {
+func NewWithOptions[K comparable, V any](opts Options[K, V]) *LRUCache[K, V] {
+	if opts.Capacity < 0 || opts.MaxBytes < 0 || (opts.Capacity == 0 && opts.MaxBytes == 0) {
+		panic("LRUCache needs a capacity or max bytes greater than 0")
+	}
+	if opts.MaxPinnedShare < 0 || opts.MaxPinnedShare > 1 {
+		panic("LRUCache max pinned share must be between 0 and 1")
+	}
+	if opts.SweepInterval < 0 {
+		panic("LRUCache sweep interval must not be negative")
+	}
+
+	weigher := opts.Weigher
+	if weigher == nil {
+		weigher = defaultWeigher[K, V]()
+	}
+
+	policy, err := newPriorityPolicy[K, V](opts.Policy, opts.Capacity)
+	if err != nil {
+		panic(err.Error())
+	}
 
-	lru.mu.Lock()         // mutex lock -- blocks RW
-	defer lru.mu.Unlock() // unlocks when the func end
+	var admission *tinyLFU[K]
+	if opts.Admission {
+		admission = newTinyLFU[K](opts.Capacity)
+	}
 
-	if node, exist := lru.cache[key]; exist {
+	lru := &LRUCache[K, V]{
+		capacity:  opts.Capacity,
+		maxBytes:  opts.MaxBytes,
+		weigher:   weigher,
+		cache:     make(map[K]*DoublyNode[K, V]),
+		policy:    policy,
+		admission: admission,
+		stop:      make(chan struct{}),
+
+		sweepInterval:  cmp.Or(opts.SweepInterval, DefaultSweepInterval),
+		maxPinnedShare: cmp.Or(opts.MaxPinnedShare, DefaultMaxPinnedShare),
+
+		loads:       make(map[K]*loadCall[V]),
+		loadErrors:  make(map[K]loadError),
+		negativeTTL: opts.NegativeTTL,
+		refresher:   opts.Refresher,
+
+		store:           opts.Store,
+		maxWriteRetries: cmp.Or(opts.MaxWriteRetries, DefaultMaxWriteRetries),
+		onStoreError:    opts.OnStoreError,
+		flushed:         make(chan struct{}),
+	}
 
-		lru.list.Remove(node)
-		lru.list.InsertAtFront(node)
+	if opts.Store != nil && opts.WriteMode == WriteBehind {
+		lru.queue = newWriteQueue[K, V](
+			cmp.Or(opts.WriteQueueSize, DefaultWriteQueueSize),
+			cmp.Or(opts.FlushBatchSize, DefaultFlushBatchSize))
+		go lru.flusher(cmp.Or(opts.FlushInterval, DefaultFlushInterval))
+	} else {
+		close(lru.flushed)
+	}
+	return lru
+}
 
-		item := node.GetData().(*CacheItem)
-		return item.value, true
+// Get returns the value of key and marks it as used. A stale entry, see
+// PutWithSoftTTL, is returned as is and refreshed in the background.
+func (lru *LRUCache[K, V]) Get(key K) (V, bool) {
+	lru.mu.Lock()      // mutex lock -- blocks RW
+	defer lru.unlock() // unlocks when the func end
+
+	now := time.Now()
+	node := lru.lookup(key, now)
+	if lru.admission != nil {
+		lru.admission.recordRead(key, node != nil)
+	}
+
+	if node != nil {
+		lru.stats.hits.Add(1)
+		lru.access(node, now)
+		lru.refreshIfStale(node, now)
+		return node.value, true
 	}
 
-	return "", false
+	lru.stats.misses.Add(1)
+	var zero V
+	return zero, false
 }
 
-func (lru *LRUCache) Put(key, value string) {
-	lru.mu.Lock()         // mutex lock -- blocks RW
-	defer lru.mu.Unlock() // unlocks when the func end
+// Put stores the value without expiration, clearing any TTL the key had.
+// It fails with ErrEntryTooLarge if the entry alone exceeds the memory budget,
+// and with ErrPinnedFull if it does not fit next to the pinned entries.
+func (lru *LRUCache[K, V]) Put(key K, value V) error {
+	return lru.PutWithTTL(key, value, 0)
+}
 
-	if node, exists := lru.cache[key]; exists {
+// PutWithTTL stores the value and expires it after ttl.
+// A ttl <= 0 means the item never expires.
+//
+// With a store, the value is written to it even if the admission filter keeps
+// it out of the cache. In write-through mode a failed store write is returned
+// and the cache is left unchanged.
+func (lru *LRUCache[K, V]) PutWithTTL(key K, value V, ttl time.Duration) error {
+	return lru.put(key, value, entryMeta{expiresAt: lru.expiryAfter(ttl)})
+}
+
+// entryMeta is what a write sets on an entry besides its value
+type entryMeta struct {
+	expiresAt time.Time   // zero for no expiration
+	tags      []string    // see PutTagged
+	refresh   *refreshTTL // see PutWithSoftTTL
+	pin       bool        // pin the entry, see PutPinned. False keeps it as it is.
+}
+
+// expiryAfter returns the expiration time of an entry written now with ttl,
+// zero if ttl <= 0, and starts the sweeper for it
+func (lru *LRUCache[K, V]) expiryAfter(ttl time.Duration) time.Time {
+	if ttl <= 0 {
+		return time.Time{}
+	}
+	lru.startSweeper()
+	return time.Now().Add(ttl)
+}
 
-		item := node.GetData().(*CacheItem)
-		item.value = value
+// put stores the value, writing it to the store if there is one
+func (lru *LRUCache[K, V]) put(key K, value V, meta entryMeta) error {
+	size := lru.weigher(key, value)
+	if lru.maxBytes > 0 && size > lru.maxBytes {
+		return ErrEntryTooLarge
+	}
 
-		lru.list.Remove(node)
-		lru.list.InsertAtFront(node)
-		return
+	queued, err := lru.lockWrite(key, value, false)
+	if err != nil {
+		return err
+	}
+	if queued {
+		lru.queue.add(key, value, false)
 	}
+	defer lru.unlock() // unlocks when the func end
 
-	if lru.list.Count() == lru.capacity {
-		tail := lru.list.last
-		if tail != nil {
-			lru.list.Remove(tail)
-			evicted := tail.GetData().(*CacheItem)
-			delete(lru.cache, evicted.key)
+	if meta.pin && !lru.canPin(lru.cache[key], size) {
+		return ErrPinLimit
+	}
+	_, err = lru.set(key, value, size, meta, time.Now())
+	return err
+}
+
+// set stores the value, evicting entries to make room, and returns its version.
+// meta replaces that of an overwritten entry. It returns 0 if the admission
+// filter rejected a new key, and fails with ErrPinnedFull if pinned entries
+// leave no room, keeping an overwritten entry as it was. Caller must hold
+// lru.mu.
+func (lru *LRUCache[K, V]) set(key K, value V, size int64, meta entryMeta, now time.Time) (uint64, error) {
+	lru.staleLoad(key)
+	if lru.admission != nil {
+		lru.admission.recordWrite(key)
+	}
+
+	node, admitted, err := lru.makeRoom(key, lru.lookup(key, now), size, now)
+	if !admitted {
+		return 0, err
+	}
+	lru.stats.sets.Add(1)
+	lru.version++
+
+	if node != nil {
+		lru.notify(node, ReasonReplaced)
+		node.value = value
+		node.version = lru.version
+		node.accessedAt = now
+		node.accessCount++
+		node.expiresAt = meta.expiresAt
+		node.refresh = meta.refresh
+		lru.expiries.track(node)
+		lru.usedBytes += size - node.size
+		lru.countPinned(node, -1)
+		node.size = size
+		lru.countPinned(node, 1)
+		lru.untag(node)
+		node.tags = meta.tags
+		lru.tag(node)
+
+		if meta.pin && !node.pinned {
+			lru.reclassify(node, true, node.priority)
+		} else {
+			lru.policy.OnAccess(node)
 		}
+		return node.version, nil
 	}
 
-	item := &CacheItem{key, value}
+	node = NewDoublyNode(key, value)
+	node.version = lru.version
+	node.createdAt, node.accessedAt, node.accessCount = now, now, 1
+	node.expiresAt = meta.expiresAt
+	node.refresh = meta.refresh
+	node.size = size
+	node.tags = meta.tags
+	node.pinned = meta.pin
+	lru.countPinned(node, 1)
+	lru.expiries.track(node)
+	lru.tag(node)
+	lru.scanIndex.add(node)
 
-	node := NewDoublyNode(item)
-	lru.list.InsertAtFront(node)
 	lru.cache[key] = node
+	lru.usedBytes += size
+	lru.policy.OnInsert(node)
+	return node.version, nil
 }
 
-func (lru *LRUCache) Delete(key string) bool {
-	lru.mu.Lock()
-	defer lru.mu.Unlock()
+// Delete removes the key and reports whether it was in the cache. With a
+// store, the key is deleted from the store too. If that fails in write-through
+// mode, the key is kept, the error goes to Options.OnStoreError and Delete
+// returns false.
+func (lru *LRUCache[K, V]) Delete(key K) bool {
+	var zero V
+	queued, err := lru.lockWrite(key, zero, true)
+	if err != nil {
+		lru.storeError(key, err)
+		return false
+	}
+	if queued {
+		lru.queue.add(key, zero, true)
+	}
+	defer lru.unlock()
 
-	if node, exists := lru.cache[key]; exists {
-		lru.list.Remove(node)
-		delete(lru.cache, key)
+	lru.staleLoad(key)
+	if node := lru.lookup(key, time.Now()); node != nil {
+		lru.removeNode(node, ReasonDeleted)
 		return true
 	}
 	return false
 }
 
-func (lru *LRUCache) Size() int {
-	lru.mu.RLock()
-	defer lru.mu.RUnlock()
-	return lru.list.Count()
+func (lru *LRUCache[K, V]) Size() int {
+	lru.mu.Lock()
+	defer lru.unlock()
+
+	lru.removeExpired(time.Now(), 0)
+	return len(lru.cache)
 }
 
-func (lru *LRUCache) Clear() {
+func (lru *LRUCache[K, V]) Clear() {
 	lru.mu.Lock()
-	defer lru.mu.Unlock()
+	defer lru.unlock()
 
-	lru.cache = make(map[string]*DoublyNode)
-	lru.list = NewDoublyLinkedList()
+	if len(lru.listeners) > 0 {
+		lru.policy.Walk(true, func(node *DoublyNode[K, V]) bool {
+			lru.notify(node, ReasonCleared)
+			return true
+		})
+	}
+
+	for _, call := range lru.loads {
+		call.stale = true
+	}
+	lru.cache = make(map[K]*DoublyNode[K, V])
+	lru.policy.Reset()
+	lru.expiries = nil
+	lru.tags = nil
+	lru.scanIndex.reset()
+	lru.usedBytes = 0
+	lru.pinnedCount, lru.pinnedBytes = 0, 0
 }
 
-func (lru *LRUCache) Contains(key string) bool {
+// Capacity returns the max number of items, 0 if only bounded by memory
+func (lru *LRUCache[K, V]) Capacity() int {
 	lru.mu.RLock()
 	defer lru.mu.RUnlock()
 
-	_, exists := lru.cache[key]
-	return exists
+	return lru.capacity
 }
 
-//for quick look
+// Policy returns the name of the eviction policy
+func (lru *LRUCache[K, V]) Policy() string {
+	return lru.policy.Name()
+}
 
-func (lru *LRUCache) Print() {
+// AdmissionStats returns how many new keys the admission filter accepted and
+// rejected when the cache was full. Both are 0 if admission is disabled.
+func (lru *LRUCache[K, V]) AdmissionStats() (accepted, rejected uint64) {
+	return lru.stats.accepted.Load(), lru.stats.rejected.Load()
+}
+
+// MaxBytes returns the memory budget, 0 if only bounded by item count
+func (lru *LRUCache[K, V]) MaxBytes() int64 {
+	return lru.maxBytes
+}
+
+// UsedBytes returns the total weight of the items currently stored
+func (lru *LRUCache[K, V]) UsedBytes() int64 {
+	lru.mu.Lock()
+	defer lru.unlock()
+
+	lru.removeExpired(time.Now(), 0)
+	return lru.usedBytes
+}
+
+func (lru *LRUCache[K, V]) Contains(key K) bool {
+	lru.mu.Lock()
+	defer lru.unlock()
+
+	return lru.lookup(key, time.Now()) != nil
+}
+
+// Expire sets the key to expire after ttl and reports whether the key exists.
+// A ttl <= 0 removes the key right away.
+func (lru *LRUCache[K, V]) Expire(key K, ttl time.Duration) bool {
+	return lru.ExpireAt(key, time.Now().Add(ttl))
+}
+
+// ExpireAt sets the key to expire at the given time and reports whether
+// the key exists. A time in the past removes the key right away.
+func (lru *LRUCache[K, V]) ExpireAt(key K, at time.Time) bool {
+	lru.mu.Lock()
+	defer lru.unlock()
 
+	now := time.Now()
+	node := lru.lookup(key, now)
+	if node == nil {
+		return false
+	}
+
+	if !at.After(now) {
+		lru.removeNode(node, ReasonExpired)
+		return true
+	}
+
+	node.expiresAt = at
+	lru.expiries.track(node)
+	lru.startSweeper()
+	return true
+}
+
+// Persist removes the expiration of the key.
+// It reports false if the key does not exist or has no expiration.
+func (lru *LRUCache[K, V]) Persist(key K) bool {
+	lru.mu.Lock()
+	defer lru.unlock()
+
+	node := lru.lookup(key, time.Now())
+	if node == nil {
+		return false
+	}
+
+	if node.expiresAt.IsZero() {
+		return false
+	}
+	node.expiresAt = time.Time{}
+	lru.expiries.track(node)
+	return true
+}
+
+// TTL returns the remaining time to live of the key, or NoExpiration if the
+// key never expires. The bool is false if the key does not exist.
+func (lru *LRUCache[K, V]) TTL(key K) (time.Duration, bool) {
+	lru.mu.Lock()
+	defer lru.unlock()
+
+	now := time.Now()
+	node := lru.lookup(key, now)
+	if node == nil {
+		return 0, false
+	}
+
+	if node.expiresAt.IsZero() {
+		return NoExpiration, true
+	}
+	return node.expiresAt.Sub(now), true
+}
+
+// Close stops the background sweeper and, in write-behind mode, flushes the
+// queued writes. The cache remains usable: expired items are then only removed
+// lazily and writes go straight to the store.
+func (lru *LRUCache[K, V]) Close() {
+	lru.closeOnce.Do(func() { close(lru.stop) })
+	<-lru.flushed
+}
+
+// lookup returns the node for key, or nil if it is missing or expired.
+// Expired nodes are removed on the way (lazy expiry). Caller must hold lru.mu.
+func (lru *LRUCache[K, V]) lookup(key K, now time.Time) *DoublyNode[K, V] {
+	node, exists := lru.cache[key]
+	if !exists {
+		return nil
+	}
+
+	if node.expired(now) {
+		lru.removeNode(node, ReasonExpired)
+		return nil
+	}
+	return node
+}
+
+func (lru *LRUCache[K, V]) startSweeper() {
+	lru.sweepOnce.Do(func() { go lru.sweeper(lru.sweepInterval) })
+}
+
+// removeNode removes a deleted or expired node. Caller must hold lru.mu.
+func (lru *LRUCache[K, V]) removeNode(node *DoublyNode[K, V], reason RemovalReason) {
+	lru.staleLoad(node.key)
+	lru.policy.OnRemove(node)
+	lru.unlink(node)
+	lru.notify(node, reason)
+
+	switch reason {
+	case ReasonDeleted:
+		lru.stats.deletes.Add(1)
+	case ReasonExpired:
+		lru.stats.expirations.Add(1)
+	}
+}
+
+// evictNode removes the node chosen by the policy. Caller must hold lru.mu.
+func (lru *LRUCache[K, V]) evictNode(node *DoublyNode[K, V]) {
+	lru.policy.OnEvict(node)
+	lru.unlink(node)
+	lru.notify(node, ReasonEvicted)
+	lru.stats.evictions.Add(1)
+
+	// get the evicted value into the store soon, Load reads it from the queue until then
+	if lru.queue != nil && lru.queue.isDirty(node.key) {
+		lru.queue.flushSoon()
+	}
+}
+
+// unlink drops node from the map, the expiry heap, the tag and scan indexes
+// and the pinned totals
+func (lru *LRUCache[K, V]) unlink(node *DoublyNode[K, V]) {
+	lru.expiries.untrack(node)
+	lru.untag(node)
+	lru.scanIndex.remove(node)
+	lru.countPinned(node, -1)
+	delete(lru.cache, node.key)
+	lru.usedBytes -= node.size
+}
+
+// makeRoom evicts entries until one of the given size fits. node is the entry
+// of key about to be overwritten, nil for a new key. It returns node, or nil if
+// node itself had to be evicted, and false if the admission filter rejected the
+// new key. It fails with ErrPinnedFull if only pinned entries are left and the
+// entry still does not fit. Caller must hold lru.mu.
+func (lru *LRUCache[K, V]) makeRoom(key K, node *DoublyNode[K, V], size int64, now time.Time) (*DoublyNode[K, V], bool, error) {
+	fits := func() bool {
+		count, bytes := len(lru.cache), lru.usedBytes+size
+		if node == nil {
+			count++
+		} else {
+			bytes -= node.size
+		}
+		return (lru.capacity == 0 || count <= lru.capacity) &&
+			(lru.maxBytes == 0 || bytes <= lru.maxBytes)
+	}
+
+	if fits() {
+		return node, true, nil
+	}
+
+	// reclaim expired items before evicting live ones
+	lru.removeExpired(now, 0)
+
+	// fail before evicting anything if the pinned entries alone leave no room
+	count, bytes := lru.pinnedCount+1, lru.pinnedBytes+size
+	if node != nil && node.pinned {
+		count, bytes = count-1, bytes-node.size
+	}
+	if (lru.capacity > 0 && count > lru.capacity) || (lru.maxBytes > 0 && bytes > lru.maxBytes) {
+		return node, false, ErrPinnedFull
+	}
+
+	if node == nil && lru.admission != nil && !fits() {
+		if victim := lru.policy.Victim(); victim != nil {
+			if !lru.admission.admit(key, victim.key) {
+				lru.stats.rejected.Add(1)
+				return nil, false, nil
+			}
+			lru.stats.accepted.Add(1)
+		}
+	}
+
+	for !fits() {
+		victim := lru.policy.Victim()
+		if victim == nil {
+			return node, false, ErrPinnedFull
+		}
+
+		lru.evictNode(victim)
+		if victim == node {
+			node = nil
+		}
+	}
+	return node, true, nil
+}
+
+// removeExpired removes up to limit expired items (all of them if limit <= 0)
+// and returns how many were removed. Caller must hold lru.mu.
+func (lru *LRUCache[K, V]) removeExpired(now time.Time, limit int) int {
+	removed := 0
+	for limit <= 0 || removed < limit {
+		node := lru.expiries.peekExpired(now)
+		if node == nil {
+			break
+		}
+		lru.removeNode(node, ReasonExpired)
+		removed++
+	}
+	return removed
+}
+
+// sweeper actively removes expired items so that keys which are never
+// read again do not hold memory until they reach the LRU tail. It also
+// forgets expired failed loads, see GetOrLoad.
+func (lru *LRUCache[K, V]) sweeper(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lru.stop:
+			return
+		case <-ticker.C:
+			// work in batches so readers are not blocked for long
+			for {
+				lru.mu.Lock()
+				n := lru.removeExpired(time.Now(), sweepBatchSize)
+				lru.unlock()
+
+				if n < sweepBatchSize {
+					break
+				}
+			}
+			lru.removeExpiredLoadErrors(time.Now())
+		}
+	}
+}
+
+// Print writes the entries to stdout, from hot to cold, for a quick look.
+//
+// Deprecated: use Coldest and Inspect, or DEBUG LRU and OBJECT on the server,
+// which report to the caller rather than to the process output.
+func (lru *LRUCache[K, V]) Print() {
 	lru.mu.RLock()
 	defer lru.mu.RUnlock()
 
-	fmt.Printf("Cache state (MRU->LRU, size: %d/%d):\n", lru.list.Count(), lru.capacity)
+	fmt.Printf("Cache state (hot->cold, policy: %s, size: %d/%d, bytes: %d/%d):\n",
+		lru.policy.Name(), len(lru.cache), lru.capacity, lru.usedBytes, lru.maxBytes)
 
-	current := lru.list.head
-	for current != nil {
-		item := current.GetData().(*CacheItem)
-		fmt.Printf("[%s : %s] ", item.key, item.value)
-		current = current.next
-	}
+	lru.policy.Walk(false, func(node *DoublyNode[K, V]) bool {
+		fmt.Printf("[%v : %v] ", node.key, node.value)
+		return true
+	})
 
 	fmt.Println()
 
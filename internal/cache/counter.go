@@ -0,0 +1,170 @@
+package cache
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+var (
+	// RemoveEntry can be returned by an Update function to delete the entry
+	RemoveEntry = errors.New("remove entry")
+
+	ErrNotInteger = errors.New("value is not an integer")
+	ErrNotFloat   = errors.New("value is not a valid float")
+	ErrOverflow   = errors.New("increment or decrement would overflow")
+)
+
+// Cloner is implemented by values that Update may change in place, such as
+// the server collections
+type Cloner[V any] interface {
+	Clone() V
+}
+
+// Update atomically replaces the value of key with the one fn returns for the
+// current value, and returns it. exists is false and value is the zero value
+// if key is missing. The expiration of an existing entry is kept. If fn returns
+// RemoveEntry the entry is deleted. If fn fails otherwise, its error is
+// returned and nothing is written. In write-through mode it fails with
+// ErrVersionConflict if a load or refresh wrote the entry while the store was
+// being written.
+//
+// fn runs under the cache lock, so it may modify a map or slice value in place
+// as long as the value is only read through View. Such a change is not undone
+// if Update fails, so fn should return its own errors before changing the
+// value. With a store or a memory budget, a value implementing Cloner is
+// cloned before fn gets it: the store may be writing the stored value
+// meanwhile, and a failed store write or a value too large for the budget
+// leaves the entry as it was.
+func (lru *LRUCache[K, V]) Update(key K, fn func(value V, exists bool) (V, error)) (V, error) {
+	var updated V
+	_, err := lru.update(key, func(node *DoublyNode[K, V]) (V, error) {
+		var value V
+		if node != nil {
+			value = node.value
+			if c, ok := any(value).(Cloner[V]); ok && (lru.store != nil || lru.maxBytes > 0) {
+				value = c.Clone()
+			}
+		}
+
+		var err error
+		updated, err = fn(value, node != nil)
+		return updated, err
+	})
+	return updated, err
+}
+
+// View calls fn with the value of key under the cache lock and reports whether
+// the key exists. Like Get it promotes the entry and counts a hit or miss.
+// fn must not call the cache.
+func (lru *LRUCache[K, V]) View(key K, fn func(value V)) bool {
+	lru.mu.Lock()
+	defer lru.unlock()
+
+	node := lru.viewNode(key, time.Now())
+	if node == nil {
+		return false
+	}
+	fn(node.value)
+	return true
+}
+
+// ViewMany is View for several keys read at the same moment: fn is called
+// with the i-th key's value and whether it exists, for all keys under one
+// hold of the cache lock. fn must not call the cache.
+func (lru *LRUCache[K, V]) ViewMany(keys []K, fn func(i int, value V, exists bool)) {
+	lru.mu.Lock()
+	defer lru.unlock()
+
+	now := time.Now()
+	for i, key := range keys {
+		var value V
+		node := lru.viewNode(key, now)
+		if node != nil {
+			value = node.value
+		}
+		fn(i, value, node != nil)
+	}
+}
+
+// viewNode looks up key for View, promoting it and counting a hit or miss.
+// Caller must hold lru.mu.
+func (lru *LRUCache[K, V]) viewNode(key K, now time.Time) *DoublyNode[K, V] {
+	node := lru.lookup(key, now)
+	if lru.admission != nil {
+		lru.admission.recordRead(key, node != nil)
+	}
+
+	if node == nil {
+		lru.stats.misses.Add(1)
+		return nil
+	}
+	lru.stats.hits.Add(1)
+	lru.access(node, now)
+	return node
+}
+
+// IncrBy atomically adds delta to the integer stored as a string at key and
+// returns the result. A missing key counts as 0.
+func IncrBy[K comparable](lru *LRUCache[K, string], key K, delta int64) (int64, error) {
+	var result int64
+	_, err := lru.Update(key, func(value string, exists bool) (string, error) {
+		var err error
+		value, result, err = addInt(value, exists, delta)
+		return value, err
+	})
+	return result, err
+}
+
+// IncrByFloat atomically adds delta to the number stored as a string at key
+// and returns the result. A missing key counts as 0.
+func IncrByFloat[K comparable](lru *LRUCache[K, string], key K, delta float64) (float64, error) {
+	var result float64
+	_, err := lru.Update(key, func(value string, exists bool) (string, error) {
+		var err error
+		value, result, err = addFloat(value, exists, delta)
+		return value, err
+	})
+	return result, err
+}
+
+// addInt adds delta to the integer in value, 0 if it does not exist, and
+// returns the new value in both forms
+func addInt(value string, exists bool, delta int64) (string, int64, error) {
+	var n int64
+	if exists {
+		var err error
+		if n, err = strconv.ParseInt(value, 10, 64); err != nil {
+			return value, 0, ErrNotInteger
+		}
+	}
+
+	if (delta > 0 && n > math.MaxInt64-delta) || (delta < 0 && n < math.MinInt64-delta) {
+		return value, 0, ErrOverflow
+	}
+	n += delta
+	return strconv.FormatInt(n, 10), n, nil
+}
+
+// addFloat is addInt for floating point numbers
+func addFloat(value string, exists bool, delta float64) (string, float64, error) {
+	var f float64
+	if exists {
+		var err error
+		if f, err = strconv.ParseFloat(value, 64); err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return value, 0, ErrNotFloat
+		}
+	}
+
+	f += delta
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return value, 0, ErrOverflow
+	}
+	return formatFloat(f), f, nil
+}
+
+// formatFloat gives the shortest decimal form of f without an exponent
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
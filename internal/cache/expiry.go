@@ -0,0 +1,66 @@
+package cache
+
+import (
+	"container/heap"
+	"time"
+)
+
+// expiryHeap is a min-heap of nodes ordered by expiration time, so the
+// next node to expire is always at index 0.
+type expiryHeap[K comparable, V any] []*DoublyNode[K, V]
+
+func (h expiryHeap[K, V]) Len() int { return len(h) }
+
+func (h expiryHeap[K, V]) Less(i, j int) bool {
+	return h[i].expiresAt.Before(h[j].expiresAt)
+}
+
+func (h expiryHeap[K, V]) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].heapIndex = i
+	h[j].heapIndex = j
+}
+
+func (h *expiryHeap[K, V]) Push(x interface{}) {
+	node := x.(*DoublyNode[K, V])
+	node.heapIndex = len(*h)
+	*h = append(*h, node)
+}
+
+func (h *expiryHeap[K, V]) Pop() interface{} {
+	old := *h
+	n := len(old)
+	node := old[n-1]
+	old[n-1] = nil
+	node.heapIndex = -1
+	*h = old[:n-1]
+	return node
+}
+
+// track adds, moves or removes the node in the heap depending on its expiresAt
+func (h *expiryHeap[K, V]) track(node *DoublyNode[K, V]) {
+	switch {
+	case node.expiresAt.IsZero() && node.heapIndex >= 0:
+		heap.Remove(h, node.heapIndex)
+	case node.expiresAt.IsZero():
+		// nothing to track
+	case node.heapIndex >= 0:
+		heap.Fix(h, node.heapIndex)
+	default:
+		heap.Push(h, node)
+	}
+}
+
+func (h *expiryHeap[K, V]) untrack(node *DoublyNode[K, V]) {
+	if node.heapIndex >= 0 {
+		heap.Remove(h, node.heapIndex)
+	}
+}
+
+// peekExpired returns the earliest node if it has expired at now
+func (h expiryHeap[K, V]) peekExpired(now time.Time) *DoublyNode[K, V] {
+	if len(h) == 0 || h[0].expiresAt.After(now) {
+		return nil
+	}
+	return h[0]
+}
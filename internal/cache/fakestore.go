@@ -0,0 +1,94 @@
+package cache
+
+import (
+	"context"
+	"sync"
+)
+
+// FakeStore is an in-memory Store for tests. It counts the calls it gets
+// and fails all of them while an error is set with SetError.
+type FakeStore[K comparable, V any] struct {
+	mu      sync.Mutex
+	data    map[K]V
+	err     error
+	loads   int
+	saves   int
+	deletes int
+}
+
+func NewFakeStore[K comparable, V any]() *FakeStore[K, V] {
+	return &FakeStore[K, V]{data: make(map[K]V)}
+}
+
+func (s *FakeStore[K, V]) Load(ctx context.Context, key K) (V, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.loads++
+	var zero V
+	if s.err != nil {
+		return zero, s.err
+	}
+	value, ok := s.data[key]
+	if !ok {
+		return zero, ErrNotFound
+	}
+	return value, nil
+}
+
+func (s *FakeStore[K, V]) Save(ctx context.Context, key K, value V) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.saves++
+	if s.err != nil {
+		return s.err
+	}
+	s.data[key] = value
+	return nil
+}
+
+func (s *FakeStore[K, V]) Delete(ctx context.Context, key K) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.deletes++
+	if s.err != nil {
+		return s.err
+	}
+	delete(s.data, key)
+	return nil
+}
+
+// SetError makes all calls fail with err, nil makes them succeed again
+func (s *FakeStore[K, V]) SetError(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.err = err
+}
+
+// Value returns the stored value of key without counting a Load
+func (s *FakeStore[K, V]) Value(key K) (V, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, ok := s.data[key]
+	return value, ok
+}
+
+// Len returns the number of stored keys
+func (s *FakeStore[K, V]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.data)
+}
+
+// Calls returns how many times each method was called, failed calls included
+func (s *FakeStore[K, V]) Calls() (loads, saves, deletes int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.loads, s.saves, s.deletes
+}
@@ -0,0 +1,80 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// FileStore is a reference Store keeping each entry as a JSON file in a
+// directory. File names are derived from fmt.Sprint(key), so keys of a type
+// whose distinct values print the same are not told apart.
+type FileStore[K comparable, V any] struct {
+	dir string
+}
+
+// NewFileStore creates dir if needed and returns a store writing into it
+func NewFileStore[K comparable, V any](dir string) (*FileStore[K, V], error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore[K, V]{dir: dir}, nil
+}
+
+func (s *FileStore[K, V]) path(key K) string {
+	sum := sha256.Sum256([]byte(fmt.Sprint(key)))
+	return filepath.Join(s.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (s *FileStore[K, V]) Load(ctx context.Context, key K) (V, error) {
+	var value V
+
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return value, ErrNotFound
+	}
+	if err != nil {
+		return value, err
+	}
+
+	err = json.Unmarshal(data, &value)
+	return value, err
+}
+
+// Save writes the value to a temporary file and renames it into place,
+// so a crash never leaves a partly written entry
+func (s *FileStore[K, V]) Save(ctx context.Context, key K, value V) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path(key))
+}
+
+func (s *FileStore[K, V]) Delete(ctx context.Context, key K) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,97 @@
+package cache
+
+// globMatch reports whether name matches a glob pattern as used by KEYS and
+// SCAN: * matches any sequence, ? any byte, [abc], [a-z] and [^abc] a class of
+// bytes, and \ escapes the next byte. A [ without a closing ] is a literal.
+func globMatch(pattern, name string) bool {
+	p, n := 0, 0
+	star, starName := -1, 0 // position of the last * and where it started matching
+
+	for n < len(name) {
+		if p < len(pattern) {
+			switch c := pattern[p]; c {
+			case '*':
+				star, starName = p, n
+				p++
+				continue
+			case '?':
+				p, n = p+1, n+1
+				continue
+			case '[':
+				if matched, end, ok := matchClass(pattern, p, name[n]); ok {
+					if matched {
+						p, n = end, n+1
+						continue
+					}
+					break
+				}
+				if name[n] == '[' {
+					p, n = p+1, n+1
+					continue
+				}
+			case '\\':
+				if p+1 < len(pattern) {
+					c, p = pattern[p+1], p+1
+				}
+				fallthrough
+			default:
+				if c == name[n] {
+					p, n = p+1, n+1
+					continue
+				}
+			}
+		}
+
+		// backtrack: let the last * match one more byte
+		if star < 0 {
+			return false
+		}
+		starName++
+		p, n = star+1, starName
+	}
+
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
+
+// matchClass matches c against the class starting at pattern[start] == '['.
+// It returns the position after the closing ], and ok false if there is none.
+func matchClass(pattern string, start int, c byte) (matched bool, end int, ok bool) {
+	i := start + 1
+	negate := i < len(pattern) && pattern[i] == '^'
+	if negate {
+		i++
+	}
+
+	for ; i < len(pattern) && pattern[i] != ']'; i++ {
+		lo := pattern[i]
+		if lo == '\\' && i+1 < len(pattern) {
+			i++
+			lo = pattern[i]
+		}
+
+		hi := lo
+		if i+2 < len(pattern) && pattern[i+1] == '-' && pattern[i+2] != ']' {
+			i += 2
+			hi = pattern[i]
+			if hi == '\\' && i+1 < len(pattern) {
+				i++
+				hi = pattern[i]
+			}
+		}
+
+		if lo > hi {
+			lo, hi = hi, lo
+		}
+		if lo <= c && c <= hi {
+			matched = true
+		}
+	}
+
+	if i >= len(pattern) {
+		return false, 0, false
+	}
+	return matched != negate, i + 1, true
+}
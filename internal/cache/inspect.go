@@ -0,0 +1,66 @@
+package cache
+
+import "time"
+
+// EntryInfo describes an entry, see Inspect
+type EntryInfo struct {
+	CreatedAt   time.Time // first stored, overwrites keep it
+	AccessedAt  time.Time // last read or write
+	AccessCount uint64    // reads and writes since created
+	Size        int64     // weight, the approximate memory use with the default weigher
+	ExpiresAt   time.Time // zero if the entry never expires
+	Pinned      bool
+	Priority    Priority
+}
+
+// Idle returns how long the entry has not been read or written
+func (info EntryInfo) Idle() time.Duration {
+	return time.Since(info.AccessedAt)
+}
+
+// Inspect returns the metadata of key. Like Peek, it does not count as an
+// access.
+func (lru *LRUCache[K, V]) Inspect(key K) (EntryInfo, bool) {
+	lru.mu.Lock()
+	defer lru.unlock()
+
+	node := lru.lookup(key, time.Now())
+	if node == nil {
+		return EntryInfo{}, false
+	}
+	return EntryInfo{
+		CreatedAt:   node.createdAt,
+		AccessedAt:  node.accessedAt,
+		AccessCount: node.accessCount,
+		Size:        node.size,
+		ExpiresAt:   node.expiresAt,
+		Pinned:      node.pinned,
+		Priority:    node.priority,
+	}, true
+}
+
+// Coldest returns up to n keys in the order they would be evicted, so
+// operators can see what is about to leave the cache. Pinned keys come last.
+func (lru *LRUCache[K, V]) Coldest(n int) []K {
+	if n <= 0 {
+		return nil
+	}
+
+	entries := lru.snapshot(true, n)
+	keys := make([]K, len(entries))
+	for i, e := range entries {
+		keys[i] = e.key
+	}
+	return keys
+}
+
+// access records a read of node. Caller must hold lru.mu.
+func (lru *LRUCache[K, V]) access(node *DoublyNode[K, V], now time.Time) {
+	node.accessedAt = now
+	node.accessCount++
+	lru.policy.OnAccess(node)
+}
+
+func (sc *ShardedCache[K, V]) Inspect(key K) (EntryInfo, bool) {
+	return sc.shard(key).Inspect(key)
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"iter"
+	"time"
+)
+
+// Iteration works on a snapshot of the entries taken under the cache mutex,
+// so it sees the cache as it was at one point in time and the loop body may
+// freely read and write the cache. "Newest" and "oldest" follow the eviction
+// policy: most to least recently used for LRU, and in general from the entry
+// evicted last to the one evicted next.
+
+type entry[K comparable, V any] struct {
+	key   K
+	value V
+}
+
+// Peek returns the value of key without promoting it or counting a hit or miss
+func (lru *LRUCache[K, V]) Peek(key K) (V, bool) {
+	lru.mu.Lock()
+	defer lru.unlock()
+
+	if node := lru.lookup(key, time.Now()); node != nil {
+		return node.value, true
+	}
+	var zero V
+	return zero, false
+}
+
+// Range calls fn for each entry from newest to oldest until fn returns false
+func (lru *LRUCache[K, V]) Range(fn func(key K, value V) bool) {
+	for key, value := range lru.All() {
+		if !fn(key, value) {
+			return
+		}
+	}
+}
+
+// All returns an iterator over the entries from newest to oldest
+func (lru *LRUCache[K, V]) All() iter.Seq2[K, V] {
+	return lru.iterate(false)
+}
+
+// Backward returns an iterator over the entries from oldest to newest
+func (lru *LRUCache[K, V]) Backward() iter.Seq2[K, V] {
+	return lru.iterate(true)
+}
+
+func (lru *LRUCache[K, V]) iterate(oldestFirst bool) iter.Seq2[K, V] {
+	return func(yield func(K, V) bool) {
+		for _, e := range lru.snapshot(oldestFirst, 0) {
+			if !yield(e.key, e.value) {
+				return
+			}
+		}
+	}
+}
+
+// Keys returns the keys from newest to oldest
+func (lru *LRUCache[K, V]) Keys() []K {
+	entries := lru.snapshot(false, 0)
+	keys := make([]K, len(entries))
+	for i, e := range entries {
+		keys[i] = e.key
+	}
+	return keys
+}
+
+// Oldest returns the entry that would be evicted next
+func (lru *LRUCache[K, V]) Oldest() (K, V, bool) {
+	return lru.first(true)
+}
+
+// Newest returns the most recently used entry
+func (lru *LRUCache[K, V]) Newest() (K, V, bool) {
+	return lru.first(false)
+}
+
+func (lru *LRUCache[K, V]) first(oldest bool) (K, V, bool) {
+	if entries := lru.snapshot(oldest, 1); len(entries) > 0 {
+		return entries[0].key, entries[0].value, true
+	}
+	var key K
+	var value V
+	return key, value, false
+}
+
+// snapshot copies up to limit live entries (all if limit <= 0) in policy order
+func (lru *LRUCache[K, V]) snapshot(oldestFirst bool, limit int) []entry[K, V] {
+	lru.mu.RLock()
+	defer lru.mu.RUnlock()
+
+	n := len(lru.cache)
+	if limit > 0 {
+		n = min(n, limit)
+	}
+	entries := make([]entry[K, V], 0, n)
+
+	now := time.Now()
+	lru.policy.Walk(oldestFirst, func(node *DoublyNode[K, V]) bool {
+		// expired entries are left to lookup and the sweeper
+		if !node.expired(now) {
+			entries = append(entries, entry[K, V]{node.key, node.value})
+		}
+		return limit <= 0 || len(entries) < limit
+	})
+	return entries
+}
@@ -0,0 +1,132 @@
+package cache
+
+// lfuBucket holds the entries sharing one access frequency, MRU at head
+type lfuBucket[K comparable, V any] struct {
+	freq int
+	list *DoublyLinkedList[K, V]
+	prev *lfuBucket[K, V] // lower frequency
+	next *lfuBucket[K, V] // higher frequency
+}
+
+// lfuPolicy evicts the least frequently used entry, and the least recently
+// used one among entries with the same frequency. All operations are O(1).
+type lfuPolicy[K comparable, V any] struct {
+	buckets map[int]*lfuBucket[K, V]
+	lowest  *lfuBucket[K, V]
+	highest *lfuBucket[K, V]
+}
+
+func newLFUPolicy[K comparable, V any]() *lfuPolicy[K, V] {
+	return &lfuPolicy[K, V]{buckets: make(map[int]*lfuBucket[K, V])}
+}
+
+func (p *lfuPolicy[K, V]) Name() string { return PolicyLFU }
+
+func (p *lfuPolicy[K, V]) OnInsert(node *DoublyNode[K, V]) {
+	node.freq = 1
+	bucket := p.buckets[1]
+	if bucket == nil {
+		bucket = p.addBucket(1, nil)
+	}
+	bucket.list.InsertAtFront(node)
+}
+
+func (p *lfuPolicy[K, V]) OnAccess(node *DoublyNode[K, V]) {
+	bucket := p.buckets[node.freq]
+
+	next := bucket.next
+	if next == nil || next.freq != node.freq+1 {
+		next = p.addBucket(node.freq+1, bucket)
+	}
+
+	p.unlink(bucket, node)
+	node.freq++
+	next.list.InsertAtFront(node)
+}
+
+func (p *lfuPolicy[K, V]) OnRemove(node *DoublyNode[K, V]) {
+	p.unlink(p.buckets[node.freq], node)
+}
+
+func (p *lfuPolicy[K, V]) OnEvict(node *DoublyNode[K, V]) {
+	p.unlink(p.buckets[node.freq], node)
+}
+
+func (p *lfuPolicy[K, V]) Victim() *DoublyNode[K, V] {
+	if p.lowest == nil {
+		return nil
+	}
+	return p.lowest.list.last
+}
+
+func (p *lfuPolicy[K, V]) Walk(coldestFirst bool, fn func(node *DoublyNode[K, V]) bool) {
+	bucket := p.highest
+	if coldestFirst {
+		bucket = p.lowest
+	}
+
+	for bucket != nil {
+		next := bucket.prev
+		if coldestFirst {
+			next = bucket.next
+		}
+
+		stopped := false
+		bucket.list.walk(coldestFirst, func(node *DoublyNode[K, V]) bool {
+			stopped = !fn(node)
+			return !stopped
+		})
+		if stopped {
+			return
+		}
+		bucket = next
+	}
+}
+
+func (p *lfuPolicy[K, V]) Reset() {
+	p.buckets = make(map[int]*lfuBucket[K, V])
+	p.lowest = nil
+	p.highest = nil
+}
+
+// addBucket creates the bucket for freq right after prev, or as the lowest one if prev is nil
+func (p *lfuPolicy[K, V]) addBucket(freq int, prev *lfuBucket[K, V]) *lfuBucket[K, V] {
+	bucket := &lfuBucket[K, V]{freq: freq, list: NewDoublyLinkedList[K, V](), prev: prev}
+
+	if prev == nil {
+		bucket.next = p.lowest
+		p.lowest = bucket
+	} else {
+		bucket.next = prev.next
+		prev.next = bucket
+	}
+
+	if bucket.next != nil {
+		bucket.next.prev = bucket
+	} else {
+		p.highest = bucket
+	}
+
+	p.buckets[freq] = bucket
+	return bucket
+}
+
+// unlink removes node from its bucket and drops the bucket once it is empty
+func (p *lfuPolicy[K, V]) unlink(bucket *lfuBucket[K, V], node *DoublyNode[K, V]) {
+	bucket.list.Remove(node)
+	if bucket.list.Count() > 0 {
+		return
+	}
+
+	if bucket.prev != nil {
+		bucket.prev.next = bucket.next
+	} else {
+		p.lowest = bucket.next
+	}
+	if bucket.next != nil {
+		bucket.next.prev = bucket.prev
+	} else {
+		p.highest = bucket.prev
+	}
+	delete(p.buckets, bucket.freq)
+}
@@ -1,16 +1,16 @@
 package cache
 
-type DoublyLinkedList struct {
-	head  *DoublyNode
-	last  *DoublyNode
+type DoublyLinkedList[K comparable, V any] struct {
+	head  *DoublyNode[K, V]
+	last  *DoublyNode[K, V]
 	count int
 }
 
-func NewDoublyLinkedList() *DoublyLinkedList {
-	return &DoublyLinkedList{}
+func NewDoublyLinkedList[K comparable, V any]() *DoublyLinkedList[K, V] {
+	return &DoublyLinkedList[K, V]{}
 }
 
-func (l *DoublyLinkedList) AttachNode(node *DoublyNode) {
+func (l *DoublyLinkedList[K, V]) AttachNode(node *DoublyNode[K, V]) {
 	if l.head == nil {
 		l.head = node
 	} else {
@@ -21,15 +21,15 @@ func (l *DoublyLinkedList) AttachNode(node *DoublyNode) {
 	l.count++
 }
 
-func (l *DoublyLinkedList) Add(data interface{}) {
-	l.AttachNode(NewDoublyNode(data))
+func (l *DoublyLinkedList[K, V]) Add(key K, value V) {
+	l.AttachNode(NewDoublyNode(key, value))
 }
 
-func (l *DoublyLinkedList) Count() int {
+func (l *DoublyLinkedList[K, V]) Count() int {
 	return l.count
 }
 
-// func (l *DoublyLinkedList) GetNext() (*DoublyNode, error) {
+// func (l *DoublyLinkedList[K, V]) GetNext() (*DoublyNode, error) {
 // 	if l.head == nil {
 // 		return nil, errors.New("list is empty")
 // 	}
@@ -37,7 +37,7 @@ func (l *DoublyLinkedList) Count() int {
 // 	return l.head, nil
 // }
 
-// func (l *DoublyLinkedList) GetPrev() (*DoublyNode, error) {
+// func (l *DoublyLinkedList[K, V]) GetPrev() (*DoublyNode, error) {
 // 	if l.last == nil {
 // 		return nil, errors.New("list is empty")
 // 	}
@@ -45,7 +45,7 @@ func (l *DoublyLinkedList) Count() int {
 // 	return l.last, nil
 // }
 
-// func (l *DoublyLinkedList) GetByIndex(index int) (*DoublyNode, error) {
+// func (l *DoublyLinkedList[K, V]) GetByIndex(index int) (*DoublyNode, error) {
 // 	if l.head == nil {
 // 		return nil, errors.New("list is emptly")
 // 	}
@@ -61,7 +61,7 @@ func (l *DoublyLinkedList) Count() int {
 // 	return node, nil
 // }
 
-func (l *DoublyLinkedList) Remove(node *DoublyNode) {
+func (l *DoublyLinkedList[K, V]) Remove(node *DoublyNode[K, V]) {
 	if node == nil {
 		return
 	}
@@ -87,7 +87,7 @@ func (l *DoublyLinkedList) Remove(node *DoublyNode) {
 	l.count--
 }
 
-func (l *DoublyLinkedList) InsertAtFront(node *DoublyNode) {
+func (l *DoublyLinkedList[K, V]) InsertAtFront(node *DoublyNode[K, V]) {
 	node.prev = nil
 	node.next = l.head
 
@@ -100,3 +100,24 @@ func (l *DoublyLinkedList) InsertAtFront(node *DoublyNode) {
 	l.head = node
 	l.count++
 }
+
+// walk calls fn from head to last, or from last to head if fromLast is set,
+// until fn returns false. fn may remove the node it is given.
+func (l *DoublyLinkedList[K, V]) walk(fromLast bool, fn func(node *DoublyNode[K, V]) bool) {
+	node := l.head
+	if fromLast {
+		node = l.last
+	}
+
+	for node != nil {
+		next := node.next
+		if fromLast {
+			next = node.prev
+		}
+
+		if !fn(node) {
+			return
+		}
+		node = next
+	}
+}
@@ -0,0 +1,132 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// LoaderFunc loads the value of a key missing from the cache
+type LoaderFunc[K comparable, V any] func(ctx context.Context, key K) (V, error)
+
+// loadCall is one in-flight loader call shared by all callers missing the same key
+type loadCall[V any] struct {
+	done  chan struct{}
+	value V
+	err   error
+	stale bool // key was written or deleted meanwhile, guarded by lru.mu
+}
+
+type loadError struct {
+	err       error
+	expiresAt time.Time
+}
+
+// GetOrLoad returns the cached value of key, or loads and stores it on a miss.
+//
+// Concurrent misses of the same key share one loader call, and its error is
+// returned to all of them. With Options.NegativeTTL set, a failed load is
+// remembered and returned to callers for that long without calling the
+// loader again. Cancelling ctx only releases this caller: the shared load
+// keeps running, without ctx's cancellation, for the other callers and to
+// fill the cache.
+//
+// The loaded value is only stored if key was not written or deleted while the
+// loader ran, as that write is at least as fresh. It is still returned to the
+// callers.
+func (lru *LRUCache[K, V]) GetOrLoad(ctx context.Context, key K, loader LoaderFunc[K, V]) (V, error) {
+	if value, ok := lru.Get(key); ok {
+		return value, nil
+	}
+
+	lru.mu.Lock()
+	now := time.Now()
+	// a load that just finished or a write may have filled the cache
+	if node := lru.lookup(key, now); node != nil {
+		value := node.value
+		lru.unlock()
+		return value, nil
+	}
+	if failed, ok := lru.loadErrors[key]; ok {
+		if now.Before(failed.expiresAt) {
+			lru.unlock()
+			var zero V
+			return zero, failed.err
+		}
+		delete(lru.loadErrors, key)
+	}
+
+	call, inFlight := lru.loads[key]
+	if !inFlight {
+		call = &loadCall[V]{done: make(chan struct{})}
+		lru.loads[key] = call
+		go lru.load(context.WithoutCancel(ctx), key, loader, call)
+	}
+	lru.unlock()
+
+	select {
+	case <-call.done:
+		return call.value, call.err
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+}
+
+func (lru *LRUCache[K, V]) load(ctx context.Context, key K, loader LoaderFunc[K, V], call *loadCall[V]) {
+	defer func() {
+		if r := recover(); r != nil {
+			call.err = fmt.Errorf("loader panic: %v", r)
+			lru.finishLoad(key, call)
+		}
+	}()
+
+	call.value, call.err = loader(ctx, key)
+	lru.finishLoad(key, call)
+}
+
+// finishLoad stores the loaded value unless the load went stale, or remembers
+// the failure for NegativeTTL, and releases the callers
+func (lru *LRUCache[K, V]) finishLoad(key K, call *loadCall[V]) {
+	lru.mu.Lock()
+	delete(lru.loads, key)
+	switch {
+	case call.err == nil && !call.stale:
+		// a value too large for the cache is still returned to the callers.
+		// It came from the source of truth, so it is not written to the store.
+		if size := lru.weigher(key, call.value); lru.maxBytes == 0 || size <= lru.maxBytes {
+			lru.set(key, call.value, size, entryMeta{}, time.Now())
+		}
+	case call.err != nil && lru.negativeTTL > 0:
+		lru.loadErrors[key] = loadError{call.err, time.Now().Add(lru.negativeTTL)}
+		lru.startSweeper()
+	}
+	lru.unlock()
+
+	close(call.done)
+}
+
+// staleLoad keeps an in-flight load of key from storing its value, as key
+// was just written or deleted. Caller must hold lru.mu.
+func (lru *LRUCache[K, V]) staleLoad(key K) {
+	if call, ok := lru.loads[key]; ok {
+		call.stale = true
+	}
+}
+
+// removeExpiredLoadErrors forgets the failed loads past NegativeTTL, so that
+// keys which are never asked for again do not stay in loadErrors
+func (lru *LRUCache[K, V]) removeExpiredLoadErrors(now time.Time) {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	for key, failed := range lru.loadErrors {
+		if !now.Before(failed.expiresAt) {
+			delete(lru.loadErrors, key)
+		}
+	}
+}
+
+func (sc *ShardedCache[K, V]) GetOrLoad(ctx context.Context, key K, loader LoaderFunc[K, V]) (V, error) {
+	return sc.shard(key).GetOrLoad(ctx, key, loader)
+}
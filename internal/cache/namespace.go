@@ -0,0 +1,120 @@
+package cache
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+const (
+	// DefaultNamespace is selected when a client connects
+	DefaultNamespace = "0"
+
+	// DefaultMaxNamespaces is used when ServerConfig.MaxNamespaces is 0
+	DefaultMaxNamespaces = 16
+)
+
+var errTooManyNamespaces = errors.New("too many namespaces")
+
+// NamespaceConfig sets the size of one namespace. Zero fields keep the
+// Capacity and MaxMemory of the ServerConfig.
+type NamespaceConfig struct {
+	Capacity  int
+	MaxMemory int64
+}
+
+// namespace is a keyspace of the server, with its own cache
+type namespace struct {
+	name    string
+	cache   *LRUCache[string, Value]
+	blocked *waiters // clients blocked in BLPOP and BRPOP
+}
+
+// session is the state of one client connection. Commands run on the
+// namespace it selected.
+type session struct {
+	*Server
+	*namespace
+}
+
+// openNamespace returns the namespace called name, creating it on first use
+func (s *Server) openNamespace(name string) (*namespace, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if ns, exists := s.namespaces[name]; exists {
+		return ns, nil
+	}
+
+	// configured namespaces do not count towards the limit
+	config, configured := s.config.Namespaces[name]
+	if !configured && len(s.namespaces) >= cmp.Or(s.config.MaxNamespaces, DefaultMaxNamespaces) {
+		return nil, errTooManyNamespaces
+	}
+
+	ns := &namespace{
+		name: name,
+		cache: NewWithOptions(Options[string, Value]{
+			Capacity:  cmp.Or(config.Capacity, s.config.Capacity),
+			MaxBytes:  cmp.Or(config.MaxMemory, s.config.MaxMemory),
+			Policy:    s.config.Policy,
+			Admission: s.config.Admission,
+
+			MaxPinnedShare: s.config.MaxPinnedShare,
+		}),
+		blocked: newWaiters(),
+	}
+	s.namespaces[name] = ns
+	return ns, nil
+}
+
+// allNamespaces returns the namespaces sorted by name
+func (s *Server) allNamespaces() []*namespace {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	names := slices.Sorted(maps.Keys(s.namespaces))
+	all := make([]*namespace, len(names))
+	for i, name := range names {
+		all[i] = s.namespaces[name]
+	}
+	return all
+}
+
+// handleSelect switches the connection to another namespace
+func (s *session) handleSelect(parts []string) string {
+	if len(parts) != 2 {
+		return "-ERR wrong number of arguments for 'SELECT' command"
+	}
+
+	ns, err := s.openNamespace(parts[1])
+	if err != nil {
+		return errorReply(err)
+	}
+	s.namespace = ns
+	return "+OK"
+}
+
+// handleFlushAll clears every namespace
+func (s *session) handleFlushAll(parts []string) string {
+	if len(parts) != 1 {
+		return "-ERR wrong number of arguments for 'FLUSHALL' command"
+	}
+
+	for _, ns := range s.allNamespaces() {
+		ns.cache.Clear()
+	}
+	return "+OK"
+}
+
+// namespaceInfo lists the key count of each namespace for INFO
+func (s *Server) namespaceInfo() string {
+	var info []string
+	for _, ns := range s.allNamespaces() {
+		info = append(info, fmt.Sprintf("ns_%s:keys=%d", ns.name, ns.cache.Size()))
+	}
+	return strings.Join(info, " ")
+}
@@ -5,43 +5,54 @@ import (
 	"fmt"
 )
 
-type DoublyNode struct {
-	data interface{}
-	next *DoublyNode
-	prev *DoublyNode
+// DoublyNode carries the cache item inline, so an entry costs a single allocation
+type DoublyNode[K comparable, V any] struct {
+	CacheItem[K, V]
+	next *DoublyNode[K, V]
+	prev *DoublyNode[K, V]
+
+	// eviction policy bookkeeping
+	freq  int   // access frequency, used by LFU
+	queue uint8 // which internal queue holds the node, used by 2Q and ARC
+
+	scanID uint64 // position in the scan index, see Scan
+}
+
+func NewDoublyNode[K comparable, V any](key K, value V) *DoublyNode[K, V] {
+	return &DoublyNode[K, V]{CacheItem: CacheItem[K, V]{key: key, value: value, heapIndex: -1}}
 }
 
-func NewDoublyNode(data interface{}) *DoublyNode {
-	return &DoublyNode{data: data}
+func (n *DoublyNode[K, V]) Key() K {
+	return n.key
 }
 
-func (n *DoublyNode) SetData(data interface{}) {
-	n.data = data
+func (n *DoublyNode[K, V]) Value() V {
+	return n.value
 }
 
-func (n *DoublyNode) GetData() interface{} {
-	return n.data
+func (n *DoublyNode[K, V]) SetValue(value V) {
+	n.value = value
 }
 
-func (n *DoublyNode) SetNext(next *DoublyNode) {
+func (n *DoublyNode[K, V]) SetNext(next *DoublyNode[K, V]) {
 	n.next = next
 }
 
-func (n *DoublyNode) GetNext() *DoublyNode {
+func (n *DoublyNode[K, V]) GetNext() *DoublyNode[K, V] {
 	return n.next
 }
 
-func (n *DoublyNode) SetPrev(prev *DoublyNode) {
+func (n *DoublyNode[K, V]) SetPrev(prev *DoublyNode[K, V]) {
 	n.prev = prev
 }
 
-func (n *DoublyNode) GetPrev() (*DoublyNode, error) {
+func (n *DoublyNode[K, V]) GetPrev() (*DoublyNode[K, V], error) {
 	if n.prev == nil {
 		return nil, errors.New("no previous node")
 	}
 	return n.prev, nil
 }
 
-func (n *DoublyNode) ToString() string {
-	return fmt.Sprintf("%v", n.data)
+func (n *DoublyNode[K, V]) ToString() string {
+	return fmt.Sprintf("%v : %v", n.key, n.value)
 }
@@ -0,0 +1,190 @@
+package cache
+
+import "fmt"
+
+const (
+	PolicyLRU  = "lru"
+	PolicyLFU  = "lfu"
+	PolicyFIFO = "fifo"
+	Policy2Q   = "2q"
+	PolicyARC  = "arc"
+)
+
+// EvictionPolicy decides which entry leaves the cache when it is over its limits.
+// The cache calls it with its mutex held, so implementations need no locking.
+type EvictionPolicy[K comparable, V any] interface {
+	Name() string
+
+	// OnInsert is called when a new entry is added
+	OnInsert(node *DoublyNode[K, V])
+	// OnAccess is called when an entry is read or overwritten
+	OnAccess(node *DoublyNode[K, V])
+	// OnRemove is called when an entry is deleted or expires
+	OnRemove(node *DoublyNode[K, V])
+	// OnEvict is called when the entry returned by Victim is evicted
+	OnEvict(node *DoublyNode[K, V])
+
+	// Victim returns the next entry to evict without removing it, nil if there is none
+	Victim() *DoublyNode[K, V]
+
+	// Walk calls fn for each entry from the hottest to the coldest one,
+	// or the other way round if coldestFirst is set, until fn returns false
+	Walk(coldestFirst bool, fn func(node *DoublyNode[K, V]) bool)
+
+	// Reset forgets all entries
+	Reset()
+}
+
+// Policies lists the names accepted by NewPolicy
+var Policies = []string{PolicyLRU, PolicyLFU, PolicyFIFO, Policy2Q, PolicyARC}
+
+// NewPolicy creates an eviction policy by name. capacity is the max number of
+// entries, policies that size internal queues fall back to the current number
+// of entries when it is 0.
+func NewPolicy[K comparable, V any](name string, capacity int) (EvictionPolicy[K, V], error) {
+	switch name {
+	case PolicyLRU, "":
+		return &lruPolicy[K, V]{list: NewDoublyLinkedList[K, V]()}, nil
+	case PolicyLFU:
+		return newLFUPolicy[K, V](), nil
+	case PolicyFIFO:
+		return &fifoPolicy[K, V]{list: NewDoublyLinkedList[K, V]()}, nil
+	case Policy2Q:
+		return newTwoQueuePolicy[K, V](capacity), nil
+	case PolicyARC:
+		return newARCPolicy[K, V](capacity), nil
+	default:
+		return nil, fmt.Errorf("unknown eviction policy '%s'", name)
+	}
+}
+
+// lruPolicy evicts the least recently used entry
+type lruPolicy[K comparable, V any] struct {
+	list *DoublyLinkedList[K, V] // MRU at head, LRU at last
+}
+
+func (p *lruPolicy[K, V]) Name() string { return PolicyLRU }
+
+func (p *lruPolicy[K, V]) OnInsert(node *DoublyNode[K, V]) {
+	p.list.InsertAtFront(node)
+}
+
+func (p *lruPolicy[K, V]) OnAccess(node *DoublyNode[K, V]) {
+	p.list.Remove(node)
+	p.list.InsertAtFront(node)
+}
+
+func (p *lruPolicy[K, V]) OnRemove(node *DoublyNode[K, V]) {
+	p.list.Remove(node)
+}
+
+func (p *lruPolicy[K, V]) OnEvict(node *DoublyNode[K, V]) {
+	p.list.Remove(node)
+}
+
+func (p *lruPolicy[K, V]) Victim() *DoublyNode[K, V] {
+	return p.list.last
+}
+
+func (p *lruPolicy[K, V]) Walk(coldestFirst bool, fn func(node *DoublyNode[K, V]) bool) {
+	p.list.walk(coldestFirst, fn)
+}
+
+func (p *lruPolicy[K, V]) Reset() {
+	p.list = NewDoublyLinkedList[K, V]()
+}
+
+// fifoPolicy evicts the oldest inserted entry, reads do not change the order
+type fifoPolicy[K comparable, V any] struct {
+	list *DoublyLinkedList[K, V] // newest at head, oldest at last
+}
+
+func (p *fifoPolicy[K, V]) Name() string { return PolicyFIFO }
+
+func (p *fifoPolicy[K, V]) OnInsert(node *DoublyNode[K, V]) {
+	p.list.InsertAtFront(node)
+}
+
+func (p *fifoPolicy[K, V]) OnAccess(node *DoublyNode[K, V]) {}
+
+func (p *fifoPolicy[K, V]) OnRemove(node *DoublyNode[K, V]) {
+	p.list.Remove(node)
+}
+
+func (p *fifoPolicy[K, V]) OnEvict(node *DoublyNode[K, V]) {
+	p.list.Remove(node)
+}
+
+func (p *fifoPolicy[K, V]) Victim() *DoublyNode[K, V] {
+	return p.list.last
+}
+
+func (p *fifoPolicy[K, V]) Walk(coldestFirst bool, fn func(node *DoublyNode[K, V]) bool) {
+	p.list.walk(coldestFirst, fn)
+}
+
+func (p *fifoPolicy[K, V]) Reset() {
+	p.list = NewDoublyLinkedList[K, V]()
+}
+
+// ghostList remembers keys of recently evicted entries, newest first.
+// 2Q and ARC use it to recognise keys that come back soon after eviction.
+type ghostList[K comparable] struct {
+	list  *DoublyLinkedList[K, struct{}]
+	index map[K]*DoublyNode[K, struct{}]
+}
+
+func newGhostList[K comparable]() *ghostList[K] {
+	return &ghostList[K]{
+		list:  NewDoublyLinkedList[K, struct{}](),
+		index: make(map[K]*DoublyNode[K, struct{}]),
+	}
+}
+
+func (g *ghostList[K]) add(key K) {
+	g.remove(key)
+	node := NewDoublyNode(key, struct{}{})
+	g.list.InsertAtFront(node)
+	g.index[key] = node
+}
+
+// remove reports whether the key was in the list
+func (g *ghostList[K]) remove(key K) bool {
+	node, exists := g.index[key]
+	if exists {
+		g.list.Remove(node)
+		delete(g.index, key)
+	}
+	return exists
+}
+
+func (g *ghostList[K]) removeOldest() {
+	if oldest := g.list.last; oldest != nil {
+		g.list.Remove(oldest)
+		delete(g.index, oldest.key)
+	}
+}
+
+func (g *ghostList[K]) trim(max int) {
+	for g.list.Count() > max {
+		g.removeOldest()
+	}
+}
+
+func (g *ghostList[K]) len() int {
+	return g.list.Count()
+}
+
+// walkLists walks several lists one after another, each in the given direction
+func walkLists[K comparable, V any](fromLast bool, fn func(node *DoublyNode[K, V]) bool, lists ...*DoublyLinkedList[K, V]) {
+	stopped := false
+	for _, list := range lists {
+		list.walk(fromLast, func(node *DoublyNode[K, V]) bool {
+			stopped = !fn(node)
+			return !stopped
+		})
+		if stopped {
+			return
+		}
+	}
+}
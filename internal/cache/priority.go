@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"errors"
+	"time"
+)
+
+// Priority is the eviction class of an entry. Entries of a lower class are
+// evicted first, the eviction policy orders the entries within a class.
+type Priority int
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0 // default for new entries
+	PriorityHigh   Priority = 1
+)
+
+// DefaultMaxPinnedShare is used when Options.MaxPinnedShare is 0
+const DefaultMaxPinnedShare = 0.5
+
+var (
+	// ErrInvalidPriority is returned by SetPriority for an unknown class
+	ErrInvalidPriority = errors.New("invalid priority")
+
+	// ErrPinLimit is returned when pinning an entry would take pinned entries
+	// over Options.MaxPinnedShare of the capacity or memory budget
+	ErrPinLimit = errors.New("pinned entries would exceed their share of the cache")
+
+	// ErrPinnedFull is returned when only pinned entries are left to evict and
+	// they leave no room for what was asked
+	ErrPinnedFull = errors.New("pinned entries leave no room in the cache")
+)
+
+// PutPinned stores the value like PutWithTTL and pins it, see Pin. If the pin
+// limit is reached, nothing is stored in the cache and it fails with
+// ErrPinLimit, the value may have been written to the store already.
+func (lru *LRUCache[K, V]) PutPinned(key K, value V, ttl time.Duration) error {
+	return lru.put(key, value, entryMeta{expiresAt: lru.expiryAfter(ttl), pin: true})
+}
+
+// Pin keeps key from being evicted until Unpin. A pinned entry still expires
+// and can be deleted, and overwriting it keeps it pinned. Pinned entries may
+// use at most Options.MaxPinnedShare of the capacity and of the memory
+// budget: Pin fails with ErrPinLimit rather than exceed it. The limit is only
+// checked when pinning, a pinned value that grows may take them over it.
+func (lru *LRUCache[K, V]) Pin(key K) error {
+	lru.mu.Lock()
+	defer lru.unlock()
+
+	node := lru.lookup(key, time.Now())
+	switch {
+	case node == nil:
+		return ErrNotFound
+	case node.pinned:
+		return nil
+	case !lru.canPin(node, node.size):
+		return ErrPinLimit
+	}
+	lru.reclassify(node, true, node.priority)
+	return nil
+}
+
+// Unpin makes key evictable again and reports whether it was pinned
+func (lru *LRUCache[K, V]) Unpin(key K) bool {
+	lru.mu.Lock()
+	defer lru.unlock()
+
+	node := lru.lookup(key, time.Now())
+	if node == nil || !node.pinned {
+		return false
+	}
+	lru.reclassify(node, false, node.priority)
+	return true
+}
+
+// SetPriority moves key to another priority class, where the policy treats
+// it as just inserted. Overwriting the entry keeps its priority.
+func (lru *LRUCache[K, V]) SetPriority(key K, priority Priority) error {
+	if priority < PriorityLow || priority > PriorityHigh {
+		return ErrInvalidPriority
+	}
+
+	lru.mu.Lock()
+	defer lru.unlock()
+
+	node := lru.lookup(key, time.Now())
+	if node == nil {
+		return ErrNotFound
+	}
+	if node.priority != priority {
+		lru.reclassify(node, node.pinned, priority)
+	}
+	return nil
+}
+
+// Pinned returns the number and total weight of the pinned entries
+func (lru *LRUCache[K, V]) Pinned() (count int, bytes int64) {
+	lru.mu.RLock()
+	defer lru.mu.RUnlock()
+
+	return lru.pinnedCount, lru.pinnedBytes
+}
+
+// PinnedShare returns the share of the cache used by pinned entries, the
+// larger of their share of the capacity and of the memory budget
+func (lru *LRUCache[K, V]) PinnedShare() float64 {
+	lru.mu.RLock()
+	defer lru.mu.RUnlock()
+
+	share := 0.0
+	if lru.capacity > 0 {
+		share = float64(lru.pinnedCount) / float64(lru.capacity)
+	}
+	if lru.maxBytes > 0 {
+		share = max(share, float64(lru.pinnedBytes)/float64(lru.maxBytes))
+	}
+	return share
+}
+
+// canPin reports whether the entry of key can be pinned with the given size.
+// node is its current entry, nil if there is none. Caller must hold lru.mu.
+func (lru *LRUCache[K, V]) canPin(node *DoublyNode[K, V], size int64) bool {
+	count, bytes := lru.pinnedCount+1, lru.pinnedBytes+size
+	if node != nil && node.pinned {
+		count, bytes = count-1, bytes-node.size
+	}
+	return (lru.capacity == 0 || float64(count) <= lru.maxPinnedShare*float64(lru.capacity)) &&
+		(lru.maxBytes == 0 || float64(bytes) <= lru.maxPinnedShare*float64(lru.maxBytes))
+}
+
+// reclassify moves a node to the class of pinned and priority. Caller must
+// hold lru.mu.
+func (lru *LRUCache[K, V]) reclassify(node *DoublyNode[K, V], pinned bool, priority Priority) {
+	lru.policy.OnRemove(node)
+	lru.countPinned(node, -1)
+	node.pinned, node.priority = pinned, priority
+	lru.countPinned(node, 1)
+	lru.policy.OnInsert(node)
+}
+
+// countPinned adds (sign 1) or removes (sign -1) a pinned node from the
+// pinned totals, and does nothing for other nodes. Caller must hold lru.mu.
+func (lru *LRUCache[K, V]) countPinned(node *DoublyNode[K, V], sign int) {
+	if node.pinned {
+		lru.pinnedCount += sign
+		lru.pinnedBytes += int64(sign) * node.size
+	}
+}
+
+// priorityPolicy runs one policy per priority class and one for the pinned
+// entries. Victims come from the lowest class that has entries, pinned
+// entries are never victims. Walk goes through the classes in eviction order.
+type priorityPolicy[K comparable, V any] struct {
+	classes []EvictionPolicy[K, V] // from PriorityLow to PriorityHigh, then pinned
+}
+
+func newPriorityPolicy[K comparable, V any](name string, capacity int) (*priorityPolicy[K, V], error) {
+	p := &priorityPolicy[K, V]{}
+	for range PriorityHigh - PriorityLow + 2 {
+		class, err := NewPolicy[K, V](name, capacity)
+		if err != nil {
+			return nil, err
+		}
+		p.classes = append(p.classes, class)
+	}
+	return p, nil
+}
+
+func (p *priorityPolicy[K, V]) class(node *DoublyNode[K, V]) EvictionPolicy[K, V] {
+	if node.pinned {
+		return p.classes[len(p.classes)-1]
+	}
+	return p.classes[node.priority-PriorityLow]
+}
+
+func (p *priorityPolicy[K, V]) Name() string { return p.classes[0].Name() }
+
+func (p *priorityPolicy[K, V]) OnInsert(node *DoublyNode[K, V]) { p.class(node).OnInsert(node) }
+func (p *priorityPolicy[K, V]) OnAccess(node *DoublyNode[K, V]) { p.class(node).OnAccess(node) }
+func (p *priorityPolicy[K, V]) OnRemove(node *DoublyNode[K, V]) { p.class(node).OnRemove(node) }
+func (p *priorityPolicy[K, V]) OnEvict(node *DoublyNode[K, V])  { p.class(node).OnEvict(node) }
+
+func (p *priorityPolicy[K, V]) Victim() *DoublyNode[K, V] {
+	for _, class := range p.classes[:len(p.classes)-1] {
+		if victim := class.Victim(); victim != nil {
+			return victim
+		}
+	}
+	return nil
+}
+
+func (p *priorityPolicy[K, V]) Walk(coldestFirst bool, fn func(node *DoublyNode[K, V]) bool) {
+	for i := range p.classes {
+		class := p.classes[i]
+		if !coldestFirst {
+			class = p.classes[len(p.classes)-1-i]
+		}
+
+		more := true
+		class.Walk(coldestFirst, func(node *DoublyNode[K, V]) bool {
+			more = fn(node)
+			return more
+		})
+		if !more {
+			return
+		}
+	}
+}
+
+func (p *priorityPolicy[K, V]) Reset() {
+	for _, class := range p.classes {
+		class.Reset()
+	}
+}
+
+func (p *priorityPolicy[K, V]) setCapacity(capacity int) {
+	for _, class := range p.classes {
+		if c, ok := class.(capacitySetter); ok {
+			c.setCapacity(capacity)
+		}
+	}
+}
+
+func (sc *ShardedCache[K, V]) PutPinned(key K, value V, ttl time.Duration) error {
+	return sc.shard(key).PutPinned(key, value, ttl)
+}
+
+func (sc *ShardedCache[K, V]) Pin(key K) error {
+	return sc.shard(key).Pin(key)
+}
+
+func (sc *ShardedCache[K, V]) Unpin(key K) bool {
+	return sc.shard(key).Unpin(key)
+}
+
+func (sc *ShardedCache[K, V]) SetPriority(key K, priority Priority) error {
+	return sc.shard(key).SetPriority(key, priority)
+}
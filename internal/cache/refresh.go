@@ -0,0 +1,97 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// refreshTTL is the soft TTL of an entry stored with PutWithSoftTTL
+type refreshTTL struct {
+	soft, hard time.Duration // hard is 0 if the entry never expires
+	staleAt    time.Time
+	refreshing bool // a refresh is in flight, guarded by lru.mu
+}
+
+// PutWithSoftTTL stores the value for stale-while-revalidate reads. Once
+// softTTL has passed, Get still returns the stale value right away and starts
+// one background refresh through Options.Refresher, which stores the new value
+// with both TTLs started again. Once hardTTL has passed the entry is expired
+// like with PutWithTTL, hardTTL <= 0 keeps it until it is refreshed, evicted
+// or deleted.
+//
+// A failed refresh keeps the stale value, and the next stale Get tries again.
+// Refreshes, failures and stale hits are counted in Stats.
+func (lru *LRUCache[K, V]) PutWithSoftTTL(key K, value V, softTTL, hardTTL time.Duration) error {
+	return lru.put(key, value, lru.softMeta(softTTL, hardTTL))
+}
+
+// softMeta returns the metadata of an entry written now by PutWithSoftTTL
+func (lru *LRUCache[K, V]) softMeta(softTTL, hardTTL time.Duration) entryMeta {
+	return entryMeta{
+		expiresAt: lru.expiryAfter(hardTTL),
+		refresh: &refreshTTL{
+			soft:    softTTL,
+			hard:    max(hardTTL, 0),
+			staleAt: time.Now().Add(softTTL),
+		},
+	}
+}
+
+// refreshIfStale counts a hit on a stale node and starts its refresh unless
+// one is in flight. Caller must hold lru.mu.
+func (lru *LRUCache[K, V]) refreshIfStale(node *DoublyNode[K, V], now time.Time) {
+	r := node.refresh
+	if r == nil || now.Before(r.staleAt) {
+		return
+	}
+
+	lru.stats.staleHits.Add(1)
+	if lru.refresher == nil || r.refreshing {
+		return
+	}
+	r.refreshing = true
+	go lru.refresh(node.key, node.version, r)
+}
+
+// refresh loads a new value for a stale entry. It is only stored if the entry
+// was not written since version, as the new write is at least as fresh. Like
+// loaded values, it comes from the source of truth and is not written to the
+// store.
+func (lru *LRUCache[K, V]) refresh(key K, version uint64, r *refreshTTL) {
+	value, err := lru.callRefresher(key)
+	size := lru.weigher(key, value)
+	if err == nil && lru.maxBytes > 0 && size > lru.maxBytes {
+		err = ErrEntryTooLarge
+	}
+
+	lru.mu.Lock()
+	defer lru.unlock()
+
+	r.refreshing = false
+	if err != nil {
+		lru.stats.refreshFailures.Add(1)
+		return
+	}
+
+	lru.stats.refreshes.Add(1)
+	now := time.Now()
+	if node := lru.lookup(key, now); node != nil && node.version == version {
+		meta := lru.softMeta(r.soft, r.hard)
+		meta.tags = node.tags
+		lru.set(key, value, size, meta, now) // keeps the stale value on ErrPinnedFull
+	}
+}
+
+func (lru *LRUCache[K, V]) callRefresher(key K) (value V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("refresher panic: %v", r)
+		}
+	}()
+	return lru.refresher(context.Background(), key)
+}
+
+func (sc *ShardedCache[K, V]) PutWithSoftTTL(key K, value V, softTTL, hardTTL time.Duration) error {
+	return sc.shard(key).PutWithSoftTTL(key, value, softTTL, hardTTL)
+}
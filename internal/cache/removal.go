@@ -0,0 +1,99 @@
+package cache
+
+// RemovalReason tells a removal listener why an entry left the cache
+type RemovalReason int
+
+const (
+	ReasonEvicted     RemovalReason = iota + 1 // evicted to make room
+	ReasonExpired                              // its TTL elapsed
+	ReasonDeleted                              // removed with Delete
+	ReasonReplaced                             // overwritten by Put, the listener gets the old value
+	ReasonCleared                              // removed by Clear
+	ReasonInvalidated                          // removed by InvalidateTag
+)
+
+func (r RemovalReason) String() string {
+	switch r {
+	case ReasonEvicted:
+		return "evicted"
+	case ReasonExpired:
+		return "expired"
+	case ReasonDeleted:
+		return "deleted"
+	case ReasonReplaced:
+		return "replaced"
+	case ReasonCleared:
+		return "cleared"
+	case ReasonInvalidated:
+		return "invalidated"
+	default:
+		return "unknown"
+	}
+}
+
+// RemovalListener is called for every entry that leaves the cache
+type RemovalListener[K comparable, V any] func(key K, value V, reason RemovalReason)
+
+type removal[K comparable, V any] struct {
+	key    K
+	value  V
+	reason RemovalReason
+}
+
+// OnRemoval registers a listener for entries leaving the cache.
+//
+// Listeners run outside the cache mutex, so they may call back into the cache.
+// They are called one at a time, in the order the removals happened. The
+// goroutine whose operation removed entries delivers the notifications before
+// that operation returns, unless another goroutine is already delivering, in
+// which case that goroutine delivers them too. This is always the case for
+// removals caused by a listener calling back into the cache.
+func (lru *LRUCache[K, V]) OnRemoval(listener RemovalListener[K, V]) {
+	lru.mu.Lock()
+	defer lru.mu.Unlock()
+
+	lru.listeners = append(lru.listeners, listener)
+}
+
+// notify queues a removal for the listeners. Caller must hold lru.mu.
+func (lru *LRUCache[K, V]) notify(node *DoublyNode[K, V], reason RemovalReason) {
+	if len(lru.listeners) > 0 {
+		lru.pending = append(lru.pending, removal[K, V]{node.key, node.value, reason})
+	}
+}
+
+// unlock releases lru.mu and then delivers queued removals to the listeners
+func (lru *LRUCache[K, V]) unlock() {
+	if len(lru.pending) == 0 || lru.notifying {
+		lru.mu.Unlock()
+		return
+	}
+
+	lru.notifying = true
+	defer func() {
+		// a panicking listener must not stop later notifications
+		if r := recover(); r != nil {
+			lru.mu.Lock()
+			lru.notifying = false
+			lru.mu.Unlock()
+			panic(r)
+		}
+	}()
+
+	for len(lru.pending) > 0 {
+		batch, listeners := lru.pending, lru.listeners
+		lru.pending = nil
+		lru.mu.Unlock()
+
+		for _, r := range batch {
+			for _, listener := range listeners {
+				listener(r.key, r.value, r.reason)
+			}
+		}
+
+		lru.mu.Lock()
+	}
+
+	lru.notifying = false
+	lru.mu.Unlock()
+}
@@ -0,0 +1,91 @@
+package cache
+
+import "errors"
+
+// max items evicted per lock acquisition when shrinking the cache
+const resizeBatchSize = 1000
+
+// ErrInvalidCapacity is returned by Resize for a negative capacity, or 0 on a
+// cache without a memory budget
+var ErrInvalidCapacity = errors.New("invalid capacity")
+
+// capacitySetter is implemented by policies that size internal queues by capacity
+type capacitySetter interface {
+	setCapacity(capacity int)
+}
+
+// Resize changes the max number of items. 0 removes the limit on caches with a
+// memory budget. Shrinking evicts the excess items from the cold end in batches,
+// releasing the mutex in between, so other operations are not blocked for long
+// on a large cache. These evictions count in stats as usual and reach removal
+// listeners once the resize is done.
+//
+// Pinned entries are never evicted: if they alone are more than capacity,
+// Resize stops at their number, which becomes the capacity, and fails with
+// ErrPinnedFull.
+func (lru *LRUCache[K, V]) Resize(capacity int) error {
+	if capacity < 0 || (capacity == 0 && lru.maxBytes == 0) {
+		return ErrInvalidCapacity
+	}
+
+	err := lru.resize(capacity)
+
+	// the evictions are delivered once resizeMu is released, so a listener
+	// may call Resize
+	lru.mu.Lock()
+	lru.unlock()
+	return err
+}
+
+// resize evicts down to capacity in batches under resizeMu, leaving the
+// removals queued for the listeners
+func (lru *LRUCache[K, V]) resize(capacity int) error {
+	lru.resizeMu.Lock()
+	defer lru.resizeMu.Unlock()
+
+	for {
+		lru.mu.Lock()
+
+		// lower the limit one batch at a time, so a Put in between only
+		// evicts for itself rather than all the excess at once
+		step := capacity
+		if capacity > 0 {
+			step = max(capacity, len(lru.cache)-resizeBatchSize)
+		}
+		lru.setCapacity(step)
+
+		for step > 0 && len(lru.cache) > step {
+			victim := lru.policy.Victim()
+			if victim == nil {
+				// only pinned entries are left
+				lru.setCapacity(len(lru.cache))
+				lru.mu.Unlock()
+				return ErrPinnedFull
+			}
+			lru.evictNode(victim)
+		}
+
+		lru.mu.Unlock()
+		if step == capacity {
+			return nil
+		}
+	}
+}
+
+// setCapacity sets the max number of items without evicting. Caller must hold
+// lru.mu.
+func (lru *LRUCache[K, V]) setCapacity(capacity int) {
+	lru.capacity = capacity
+	if p, ok := lru.policy.(capacitySetter); ok {
+		p.setCapacity(capacity)
+	}
+}
+
+func (p *twoQueuePolicy[K, V]) setCapacity(capacity int) {
+	p.capacity = capacity
+}
+
+func (p *arcPolicy[K, V]) setCapacity(capacity int) {
+	p.capacity = capacity
+	p.p = min(p.p, p.size())
+}
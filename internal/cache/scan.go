@@ -0,0 +1,100 @@
+package cache
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultScanCount is the number of entries a Scan examines when count <= 0
+const DefaultScanCount = 10
+
+// scanIndex lists the entries in insertion order for Scan. Each entry gets an
+// increasing id when inserted, kept when it is overwritten, and a cursor is
+// the last id a Scan examined. Removed entries leave a hole until more than
+// half of the list are holes, then the list is compacted. Compacting keeps the
+// order, so cursors stay valid.
+type scanIndex[K comparable, V any] struct {
+	entries []scanEntry[K, V]
+	removed int    // holes in entries
+	lastID  uint64 // not reset by Clear, so old cursors do not skip new entries
+}
+
+type scanEntry[K comparable, V any] struct {
+	id   uint64
+	node *DoublyNode[K, V] // nil once removed
+}
+
+// minScanCompaction keeps small lists from being compacted over and over
+const minScanCompaction = 64
+
+func (s *scanIndex[K, V]) add(node *DoublyNode[K, V]) {
+	s.lastID++
+	node.scanID = s.lastID
+	s.entries = append(s.entries, scanEntry[K, V]{id: s.lastID, node: node})
+}
+
+func (s *scanIndex[K, V]) remove(node *DoublyNode[K, V]) {
+	i := s.search(node.scanID - 1)
+	if i == len(s.entries) || s.entries[i].node != node {
+		return
+	}
+	s.entries[i].node = nil
+	s.removed++
+
+	if s.removed > minScanCompaction && s.removed > len(s.entries)/2 {
+		live := s.entries[:0]
+		for _, e := range s.entries {
+			if e.node != nil {
+				live = append(live, e)
+			}
+		}
+		clear(s.entries[len(live):])
+		s.entries, s.removed = live, 0
+	}
+}
+
+func (s *scanIndex[K, V]) reset() {
+	s.entries, s.removed = nil, 0
+}
+
+// search returns the position of the first entry after cursor
+func (s *scanIndex[K, V]) search(cursor uint64) int {
+	return sort.Search(len(s.entries), func(i int) bool { return s.entries[i].id > cursor })
+}
+
+// Scan returns the keys of up to count entries after cursor, starting from
+// cursor 0, and the cursor to pass to the next call, 0 once all entries have
+// been seen. Each call holds the lock only for its own entries, so a large
+// cache can be scanned while serving requests. Every key present for the
+// whole scan is returned exactly once. Keys added meanwhile may or may not be
+// returned. A call may return fewer than count keys, and none, while the scan
+// is not over.
+func (lru *LRUCache[K, V]) Scan(cursor uint64, count int) ([]K, uint64) {
+	if count <= 0 {
+		count = DefaultScanCount
+	}
+
+	lru.mu.RLock()
+	defer lru.mu.RUnlock()
+
+	entries := lru.scanIndex.entries
+	now := time.Now()
+	keys := make([]K, 0, count)
+
+	i := lru.scanIndex.search(cursor)
+	for examined := 0; i < len(entries) && examined < count; i++ {
+		node := entries[i].node
+		if node == nil {
+			continue
+		}
+		examined++
+		if !node.expired(now) {
+			keys = append(keys, node.key)
+		}
+	}
+
+	if i == len(entries) {
+		return keys, 0
+	}
+	return keys, entries[i-1].id
+}
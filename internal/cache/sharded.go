@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"hash/maphash"
+	"time"
+)
+
+// DefaultShardCount is a good fit for servers with up to a few dozen cores
+const DefaultShardCount = 16
+
+// ShardedCache spreads keys over independent LRUCache segments selected by key
+// hash, so operations on different shards do not contend on the same mutex.
+// Capacity is split evenly between shards and LRU order is kept per shard,
+// so eviction is an approximation of a global LRU.
+type ShardedCache[K comparable, V any] struct {
+	shards []*LRUCache[K, V]
+	seed   maphash.Seed
+}
+
+func NewSharded[K comparable, V any](shardCount, capacity int) *ShardedCache[K, V] {
+	if capacity <= 0 {
+		panic("ShardedCache capacity must be greater than 0")
+	}
+
+	return NewShardedWithOptions(shardCount, Options[K, V]{Capacity: capacity})
+}
+
+// NewShardedWithOptions splits opts.Capacity, opts.MaxBytes and
+// opts.WriteQueueSize between shardCount shards, rounding up so the total is
+// never below the requested limits
+func NewShardedWithOptions[K comparable, V any](shardCount int, opts Options[K, V]) *ShardedCache[K, V] {
+	if shardCount <= 0 {
+		panic("ShardedCache shard count must be greater than 0")
+	}
+
+	perShard := opts
+	perShard.Capacity = (opts.Capacity + shardCount - 1) / shardCount
+	perShard.MaxBytes = (opts.MaxBytes + int64(shardCount) - 1) / int64(shardCount)
+	perShard.WriteQueueSize = (opts.WriteQueueSize + shardCount - 1) / shardCount
+
+	sc := &ShardedCache[K, V]{
+		shards: make([]*LRUCache[K, V], shardCount),
+		seed:   maphash.MakeSeed(),
+	}
+	for i := range sc.shards {
+		sc.shards[i] = NewWithOptions(perShard)
+	}
+	return sc
+}
+
+func (sc *ShardedCache[K, V]) shard(key K) *LRUCache[K, V] {
+	return sc.shards[maphash.Comparable(sc.seed, key)%uint64(len(sc.shards))]
+}
+
+func (sc *ShardedCache[K, V]) Get(key K) (V, bool) {
+	return sc.shard(key).Get(key)
+}
+
+func (sc *ShardedCache[K, V]) Put(key K, value V) error {
+	return sc.shard(key).Put(key, value)
+}
+
+func (sc *ShardedCache[K, V]) PutWithTTL(key K, value V, ttl time.Duration) error {
+	return sc.shard(key).PutWithTTL(key, value, ttl)
+}
+
+func (sc *ShardedCache[K, V]) PutTagged(key K, value V, ttl time.Duration, tags ...string) error {
+	return sc.shard(key).PutTagged(key, value, ttl, tags...)
+}
+
+// InvalidateTag removes the tagged entries from every shard
+func (sc *ShardedCache[K, V]) InvalidateTag(tag string) int {
+	removed := 0
+	for _, shard := range sc.shards {
+		removed += shard.InvalidateTag(tag)
+	}
+	return removed
+}
+
+func (sc *ShardedCache[K, V]) GetWithVersion(key K) (V, uint64, bool) {
+	return sc.shard(key).GetWithVersion(key)
+}
+
+func (sc *ShardedCache[K, V]) CompareAndSwap(key K, version uint64, value V) (uint64, error) {
+	return sc.shard(key).CompareAndSwap(key, version, value)
+}
+
+func (sc *ShardedCache[K, V]) Delete(key K) bool {
+	return sc.shard(key).Delete(key)
+}
+
+func (sc *ShardedCache[K, V]) Peek(key K) (V, bool) {
+	return sc.shard(key).Peek(key)
+}
+
+func (sc *ShardedCache[K, V]) Load(ctx context.Context, key K) (V, error) {
+	return sc.shard(key).Load(ctx, key)
+}
+
+func (sc *ShardedCache[K, V]) Contains(key K) bool {
+	return sc.shard(key).Contains(key)
+}
+
+// Size sums the shard sizes. Shards are locked one after another,
+// so under concurrent writes the result is not a point-in-time snapshot.
+func (sc *ShardedCache[K, V]) Size() int {
+	size := 0
+	for _, shard := range sc.shards {
+		size += shard.Size()
+	}
+	return size
+}
+
+func (sc *ShardedCache[K, V]) Clear() {
+	for _, shard := range sc.shards {
+		shard.Clear()
+	}
+}
+
+// OnRemoval registers the listener on every shard. Ordering guarantees hold
+// per shard only.
+func (sc *ShardedCache[K, V]) OnRemoval(listener RemovalListener[K, V]) {
+	for _, shard := range sc.shards {
+		shard.OnRemoval(listener)
+	}
+}
+
+// Resize splits capacity between the shards like NewShardedWithOptions and
+// resizes them one after another. A shard whose pinned entries block the
+// resize does not stop the others, ErrPinnedFull is returned at the end.
+func (sc *ShardedCache[K, V]) Resize(capacity int) error {
+	perShard := (capacity + len(sc.shards) - 1) / len(sc.shards)
+	var pinnedFull error
+	for _, shard := range sc.shards {
+		err := shard.Resize(perShard)
+		if errors.Is(err, ErrPinnedFull) {
+			pinnedFull = err
+		} else if err != nil {
+			return err
+		}
+	}
+	return pinnedFull
+}
+
+func (sc *ShardedCache[K, V]) ShardCount() int {
+	return len(sc.shards)
+}
+
+// Flush flushes the write-behind writes of all shards
+func (sc *ShardedCache[K, V]) Flush() error {
+	var errs []error
+	for _, shard := range sc.shards {
+		errs = append(errs, shard.Flush())
+	}
+	return errors.Join(errs...)
+}
+
+// Close stops the background sweepers and flushers of all shards
+func (sc *ShardedCache[K, V]) Close() {
+	for _, shard := range sc.shards {
+		shard.Close()
+	}
+}
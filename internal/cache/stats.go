@@ -0,0 +1,106 @@
+package cache
+
+import "sync/atomic"
+
+// Stats is a snapshot of the cache counters since creation or the last ResetStats
+type Stats struct {
+	Hits        uint64
+	Misses      uint64
+	Sets        uint64
+	Deletes     uint64
+	Evictions   uint64
+	Expirations uint64
+
+	// new keys the admission filter accepted or rejected when the cache was full
+	AdmissionAccepted uint64
+	AdmissionRejected uint64
+
+	// soft TTL: hits on stale entries, and refreshes that succeeded or failed
+	StaleHits       uint64
+	Refreshes       uint64
+	RefreshFailures uint64
+}
+
+// HitRatio returns hits / (hits + misses), 0 if there were no lookups
+func (s Stats) HitRatio() float64 {
+	if lookups := s.Hits + s.Misses; lookups > 0 {
+		return float64(s.Hits) / float64(lookups)
+	}
+	return 0
+}
+
+func (s Stats) add(other Stats) Stats {
+	s.Hits += other.Hits
+	s.Misses += other.Misses
+	s.Sets += other.Sets
+	s.Deletes += other.Deletes
+	s.Evictions += other.Evictions
+	s.Expirations += other.Expirations
+	s.AdmissionAccepted += other.AdmissionAccepted
+	s.AdmissionRejected += other.AdmissionRejected
+	s.StaleHits += other.StaleHits
+	s.Refreshes += other.Refreshes
+	s.RefreshFailures += other.RefreshFailures
+	return s
+}
+
+// statsCounters are updated atomically so reading them never takes the cache lock
+type statsCounters struct {
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	sets        atomic.Uint64
+	deletes     atomic.Uint64
+	evictions   atomic.Uint64
+	expirations atomic.Uint64
+	accepted    atomic.Uint64
+	rejected    atomic.Uint64
+
+	staleHits       atomic.Uint64
+	refreshes       atomic.Uint64
+	refreshFailures atomic.Uint64
+}
+
+// Stats returns a snapshot of the cache counters
+func (lru *LRUCache[K, V]) Stats() Stats {
+	c := &lru.stats
+	return Stats{
+		Hits:              c.hits.Load(),
+		Misses:            c.misses.Load(),
+		Sets:              c.sets.Load(),
+		Deletes:           c.deletes.Load(),
+		Evictions:         c.evictions.Load(),
+		Expirations:       c.expirations.Load(),
+		AdmissionAccepted: c.accepted.Load(),
+		AdmissionRejected: c.rejected.Load(),
+		StaleHits:         c.staleHits.Load(),
+		Refreshes:         c.refreshes.Load(),
+		RefreshFailures:   c.refreshFailures.Load(),
+	}
+}
+
+// ResetStats sets all counters back to 0
+func (lru *LRUCache[K, V]) ResetStats() {
+	c := &lru.stats
+	for _, counter := range []*atomic.Uint64{
+		&c.hits, &c.misses, &c.sets, &c.deletes,
+		&c.evictions, &c.expirations, &c.accepted, &c.rejected,
+		&c.staleHits, &c.refreshes, &c.refreshFailures,
+	} {
+		counter.Store(0)
+	}
+}
+
+// Stats sums the counters of all shards
+func (sc *ShardedCache[K, V]) Stats() Stats {
+	var total Stats
+	for _, shard := range sc.shards {
+		total = total.add(shard.Stats())
+	}
+	return total
+}
+
+func (sc *ShardedCache[K, V]) ResetStats() {
+	for _, shard := range sc.shards {
+		shard.ResetStats()
+	}
+}
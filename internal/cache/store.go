@@ -0,0 +1,174 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// WriteMode selects how writes reach the Store behind a cache
+type WriteMode int
+
+const (
+	WriteThrough WriteMode = iota // Put and Delete write to the store before returning
+	WriteBehind                   // writes are queued and flushed to the store in the background
+)
+
+const (
+	// write-behind defaults, used when the Options field is 0
+	DefaultFlushInterval   = time.Second
+	DefaultFlushBatchSize  = 100
+	DefaultWriteQueueSize  = 10000
+	DefaultMaxWriteRetries = 3
+)
+
+var (
+	// ErrNotFound is returned by Store.Load and LRUCache.Load for missing keys
+	ErrNotFound = errors.New("key not found")
+
+	// ErrNoStore is returned by Load and Flush on a cache without a store
+	ErrNoStore = errors.New("cache has no store")
+)
+
+// Store is a persistent store behind the cache, set with Options.Store.
+// It must be safe for concurrent use.
+type Store[K comparable, V any] interface {
+	// Load returns the stored value of key, or ErrNotFound
+	Load(ctx context.Context, key K) (V, error)
+	Save(ctx context.Context, key K, value V) error
+	// Delete removes key, deleting a missing key is not an error
+	Delete(ctx context.Context, key K) error
+}
+
+// Load returns the value of key from the cache, reading it from the store on
+// a miss. Misses of the same key share one store read, as with GetOrLoad.
+// Write-behind writes that are not flushed yet are seen by Load.
+func (lru *LRUCache[K, V]) Load(ctx context.Context, key K) (V, error) {
+	if lru.store == nil {
+		var zero V
+		return zero, ErrNoStore
+	}
+	return lru.GetOrLoad(ctx, key, lru.loadFromStore)
+}
+
+func (lru *LRUCache[K, V]) loadFromStore(ctx context.Context, key K) (V, error) {
+	if lru.queue != nil {
+		if w, ok := lru.queue.pending(key); ok {
+			if w.deleted {
+				var zero V
+				return zero, ErrNotFound
+			}
+			return w.value, nil
+		}
+	}
+	return lru.store.Load(ctx, key)
+}
+
+// Flush writes all queued write-behind writes to the store now and returns
+// the errors of the failed ones. Failed writes stay queued for a retry.
+// It does nothing in write-through mode.
+func (lru *LRUCache[K, V]) Flush() error {
+	if lru.store == nil {
+		return ErrNoStore
+	}
+	if lru.queue == nil {
+		return nil
+	}
+	return lru.flush(lru.maxWriteRetries)
+}
+
+// lockWrite locks lru.mu for a Put or Delete of key and reports whether the
+// caller must queue the write for write-behind. In write-through mode the
+// store is written first and its error is returned without locking. Store
+// writes of the same key are applied to the cache in the same order.
+func (lru *LRUCache[K, V]) lockWrite(key K, value V, deleted bool) (queued bool, err error) {
+	if lru.store == nil {
+		lru.mu.Lock()
+		return false, nil
+	}
+	if lru.queue != nil && lru.queue.reserve() {
+		lru.mu.Lock()
+		return true, nil
+	}
+
+	lru.writeMu.Lock()
+	if deleted {
+		err = lru.store.Delete(context.Background(), key)
+	} else {
+		err = lru.store.Save(context.Background(), key, value)
+	}
+	if err != nil {
+		lru.writeMu.Unlock()
+		return false, err
+	}
+
+	lru.mu.Lock()
+	lru.writeMu.Unlock()
+	return false, nil
+}
+
+// storeError reports a write given up on
+func (lru *LRUCache[K, V]) storeError(key K, err error) {
+	if lru.onStoreError != nil {
+		lru.onStoreError(key, err)
+	}
+}
+
+// flush writes the queued writes in batches. Only the writes queued when it
+// starts are written, so failed ones are retried on the next flush rather than
+// in a loop. A failed write is given up after maxRetries retries.
+func (lru *LRUCache[K, V]) flush(maxRetries int) error {
+	q := lru.queue
+	q.flushMu.Lock()
+	defer q.flushMu.Unlock()
+
+	var errs []error
+	for remaining := q.len(); remaining > 0; {
+		batch := q.take(min(remaining, q.batchSize))
+		if len(batch) == 0 {
+			break
+		}
+		remaining -= len(batch)
+
+		for _, w := range batch {
+			if w.deleted {
+				w.err = lru.store.Delete(context.Background(), w.key)
+			} else {
+				w.err = lru.store.Save(context.Background(), w.key, w.value)
+			}
+			if w.err != nil {
+				errs = append(errs, w.err)
+			}
+		}
+
+		for _, w := range q.done(batch, maxRetries) {
+			lru.storeError(w.key, w.err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// flusher flushes write-behind writes every interval, or sooner when the queue
+// fills up a batch or a dirty entry is evicted. When the cache is closed it
+// flushes what is left and switches the cache to write-through.
+func (lru *LRUCache[K, V]) flusher(interval time.Duration) {
+	defer close(lru.flushed)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-lru.stop:
+			// writers that already reserved a slot are still flushed
+			for !lru.queue.closeIfIdle() {
+				lru.flush(0)
+			}
+			return
+		case <-ticker.C:
+			lru.flush(lru.maxWriteRetries)
+		case <-lru.queue.kick:
+			lru.flush(lru.maxWriteRetries)
+		}
+	}
+}
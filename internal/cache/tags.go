@@ -0,0 +1,81 @@
+package cache
+
+import (
+	"slices"
+	"time"
+)
+
+// PutTagged stores the value like PutWithTTL and attaches tags to it, so it
+// can be removed with InvalidateTag. The tags replace those of an existing
+// entry, and a plain Put of the key removes them. Update and the counters
+// keep them.
+func (lru *LRUCache[K, V]) PutTagged(key K, value V, ttl time.Duration, tags ...string) error {
+	tags = slices.Compact(slices.Sorted(slices.Values(tags)))
+	return lru.put(key, value, entryMeta{expiresAt: lru.expiryAfter(ttl), tags: tags})
+}
+
+// Tags returns the sorted tags of key, without promoting it
+func (lru *LRUCache[K, V]) Tags(key K) []string {
+	lru.mu.RLock()
+	defer lru.mu.RUnlock()
+
+	node, exists := lru.cache[key]
+	if !exists || node.expired(time.Now()) {
+		return nil
+	}
+	return slices.Clone(node.tags)
+}
+
+// InvalidateTag removes every entry tagged with tag and returns how many were
+// removed. Removal listeners see ReasonInvalidated. The entries stay in the
+// store, if any, so the next Load reads them again.
+func (lru *LRUCache[K, V]) InvalidateTag(tag string) int {
+	lru.mu.Lock()
+	defer lru.unlock()
+
+	now := time.Now()
+	removed := 0
+	for key := range lru.tags[tag] {
+		// lookup removes expired entries, both remove key from lru.tags
+		if node := lru.lookup(key, now); node != nil {
+			lru.removeNode(node, ReasonInvalidated)
+			removed++
+		}
+	}
+	return removed
+}
+
+// TagCount returns the number of tags with at least one entry
+func (lru *LRUCache[K, V]) TagCount() int {
+	lru.mu.RLock()
+	defer lru.mu.RUnlock()
+
+	return len(lru.tags)
+}
+
+// tag adds node to the index of its tags. Caller must hold lru.mu.
+func (lru *LRUCache[K, V]) tag(node *DoublyNode[K, V]) {
+	for _, tag := range node.tags {
+		keys, exists := lru.tags[tag]
+		if !exists {
+			if lru.tags == nil {
+				lru.tags = make(map[string]map[K]struct{})
+			}
+			keys = make(map[K]struct{})
+			lru.tags[tag] = keys
+		}
+		keys[node.key] = struct{}{}
+	}
+}
+
+// untag removes node from the index, dropping tags left without keys so the
+// index does not outgrow the cache. Caller must hold lru.mu.
+func (lru *LRUCache[K, V]) untag(node *DoublyNode[K, V]) {
+	for _, tag := range node.tags {
+		keys := lru.tags[tag]
+		delete(keys, node.key)
+		if len(keys) == 0 {
+			delete(lru.tags, tag)
+		}
+	}
+}
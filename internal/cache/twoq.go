@@ -0,0 +1,99 @@
+package cache
+
+const (
+	queueA1in uint8 = iota + 1 // 2Q: entries seen once, FIFO
+	queueAm                    // 2Q: entries seen again after eviction, LRU
+)
+
+// twoQueuePolicy is the full 2Q algorithm (Johnson and Shasha). New entries go
+// to a FIFO queue and only move to the main LRU queue when they are requested
+// again shortly after being evicted, so a scan of one-off keys only churns the
+// FIFO queue and leaves the hot entries alone.
+type twoQueuePolicy[K comparable, V any] struct {
+	capacity int
+	a1in     *DoublyLinkedList[K, V] // newest at head
+	am       *DoublyLinkedList[K, V] // MRU at head
+	a1out    *ghostList[K]           // keys recently evicted from a1in
+}
+
+func newTwoQueuePolicy[K comparable, V any](capacity int) *twoQueuePolicy[K, V] {
+	return &twoQueuePolicy[K, V]{
+		capacity: capacity,
+		a1in:     NewDoublyLinkedList[K, V](),
+		am:       NewDoublyLinkedList[K, V](),
+		a1out:    newGhostList[K](),
+	}
+}
+
+func (p *twoQueuePolicy[K, V]) Name() string { return Policy2Q }
+
+// sizes recommended by the paper: a1in 25% and a1out 50% of the capacity
+func (p *twoQueuePolicy[K, V]) limits() (kin, kout int) {
+	c := p.capacity
+	if c == 0 {
+		c = p.a1in.Count() + p.am.Count()
+	}
+	return max(c/4, 1), max(c/2, 1)
+}
+
+func (p *twoQueuePolicy[K, V]) OnInsert(node *DoublyNode[K, V]) {
+	if p.a1out.remove(node.key) {
+		node.queue = queueAm
+		p.am.InsertAtFront(node)
+		return
+	}
+
+	node.queue = queueA1in
+	p.a1in.InsertAtFront(node)
+}
+
+func (p *twoQueuePolicy[K, V]) OnAccess(node *DoublyNode[K, V]) {
+	// hits in a1in are deliberately ignored, they are likely correlated references
+	if node.queue == queueAm {
+		p.am.Remove(node)
+		p.am.InsertAtFront(node)
+	}
+}
+
+func (p *twoQueuePolicy[K, V]) OnRemove(node *DoublyNode[K, V]) {
+	p.queueOf(node).Remove(node)
+}
+
+func (p *twoQueuePolicy[K, V]) OnEvict(node *DoublyNode[K, V]) {
+	p.queueOf(node).Remove(node)
+
+	if node.queue == queueA1in {
+		_, kout := p.limits()
+		p.a1out.add(node.key)
+		p.a1out.trim(kout)
+	}
+}
+
+func (p *twoQueuePolicy[K, V]) Victim() *DoublyNode[K, V] {
+	kin, _ := p.limits()
+	if p.a1in.Count() > kin || p.am.Count() == 0 {
+		return p.a1in.last
+	}
+	return p.am.last
+}
+
+func (p *twoQueuePolicy[K, V]) Walk(coldestFirst bool, fn func(node *DoublyNode[K, V]) bool) {
+	if coldestFirst {
+		walkLists(coldestFirst, fn, p.a1in, p.am)
+	} else {
+		walkLists(coldestFirst, fn, p.am, p.a1in)
+	}
+}
+
+func (p *twoQueuePolicy[K, V]) Reset() {
+	p.a1in = NewDoublyLinkedList[K, V]()
+	p.am = NewDoublyLinkedList[K, V]()
+	p.a1out = newGhostList[K]()
+}
+
+func (p *twoQueuePolicy[K, V]) queueOf(node *DoublyNode[K, V]) *DoublyLinkedList[K, V] {
+	if node.queue == queueAm {
+		return p.am
+	}
+	return p.a1in
+}
@@ -0,0 +1,257 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// HashValue maps fields to values, see HSET
+type HashValue struct {
+	fields map[string]string
+	bytes  int64 // total length of the fields and values
+}
+
+func NewHashValue() *HashValue {
+	return &HashValue{fields: make(map[string]string)}
+}
+
+func (h *HashValue) Type() string { return "hash" }
+
+func (h *HashValue) Size() int64 {
+	return h.bytes + int64(len(h.fields))*collectionOverhead
+}
+
+func (h *HashValue) Len() int {
+	return len(h.fields)
+}
+
+func (h *HashValue) Get(field string) (string, bool) {
+	value, exists := h.fields[field]
+	return value, exists
+}
+
+// Set sets a field and reports whether it is new
+func (h *HashValue) Set(field, value string) bool {
+	old, exists := h.fields[field]
+	if exists {
+		h.bytes -= int64(len(old))
+	} else {
+		h.bytes += int64(len(field))
+	}
+	h.fields[field] = value
+	h.bytes += int64(len(value))
+	return !exists
+}
+
+// Delete removes a field and reports whether it existed
+func (h *HashValue) Delete(field string) bool {
+	value, exists := h.fields[field]
+	if exists {
+		delete(h.fields, field)
+		h.bytes -= int64(len(field) + len(value))
+	}
+	return exists
+}
+
+func (h *HashValue) Clone() Value {
+	return &HashValue{fields: maps.Clone(h.fields), bytes: h.bytes}
+}
+
+// MarshalJSON encodes the hash as an object, for stores
+func (h *HashValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(h.fields)
+}
+
+// updateHash runs fn on the hash at key, created empty if missing, and deletes
+// the key if fn leaves the hash empty
+func (s *session) updateHash(key string, fn func(hash *HashValue) error) error {
+	_, err := s.cache.Update(key, func(value Value, exists bool) (Value, error) {
+		hash, ok := value.(*HashValue)
+		if !exists {
+			hash = NewHashValue()
+		} else if !ok {
+			return value, ErrWrongType
+		}
+
+		if err := fn(hash); err != nil {
+			return value, err
+		}
+		if hash.Len() == 0 {
+			return hash, RemoveEntry
+		}
+		return hash, nil
+	})
+	return err
+}
+
+// viewHash runs fn on the hash at key and reports whether the key exists
+func (s *session) viewHash(key string, fn func(hash *HashValue)) (bool, error) {
+	var err error
+	exists := s.cache.View(key, func(value Value) {
+		if hash, ok := value.(*HashValue); ok {
+			fn(hash)
+		} else {
+			err = ErrWrongType
+		}
+	})
+	return exists, err
+}
+
+func (s *session) handleHSet(parts []string) string {
+	if len(parts) < 4 || len(parts)%2 != 0 {
+		return "-ERR wrong number of arguments for 'HSET' command"
+	}
+
+	added := 0
+	err := s.updateHash(parts[1], func(hash *HashValue) error {
+		for i := 2; i < len(parts); i += 2 {
+			if hash.Set(parts[i], parts[i+1]) {
+				added++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errorReply(err)
+	}
+	return fmt.Sprintf(":%d", added)
+}
+
+func (s *session) handleHGet(parts []string) string {
+	if len(parts) != 3 {
+		return "-ERR wrong number of arguments for 'HGET' command"
+	}
+
+	var value string
+	var found bool
+	exists, err := s.viewHash(parts[1], func(hash *HashValue) {
+		value, found = hash.Get(parts[2])
+	})
+	switch {
+	case err != nil:
+		return errorReply(err)
+	case !exists:
+		return "-ERR key not found"
+	case !found:
+		return "-ERR field not found"
+	}
+	return fmt.Sprintf("+%s", value)
+}
+
+// handleHMGet replies with one element per field, nil for missing fields
+func (s *session) handleHMGet(parts []string) string {
+	if len(parts) < 3 {
+		return "-ERR wrong number of arguments for 'HMGET' command"
+	}
+
+	elements := make([]string, len(parts)-2)
+	for i := range elements {
+		elements[i] = nilElement
+	}
+	_, err := s.viewHash(parts[1], func(hash *HashValue) {
+		for i, field := range parts[2:] {
+			if value, found := hash.Get(field); found {
+				elements[i] = "+" + value
+			}
+		}
+	})
+	if err != nil {
+		return errorReply(err)
+	}
+	return arrayReply(elements)
+}
+
+// handleHGetAll replies with fields and values alternating, sorted by field
+func (s *session) handleHGetAll(parts []string) string {
+	if len(parts) != 2 {
+		return "-ERR wrong number of arguments for 'HGETALL' command"
+	}
+
+	var elements []string
+	_, err := s.viewHash(parts[1], func(hash *HashValue) {
+		for _, field := range slices.Sorted(maps.Keys(hash.fields)) {
+			elements = append(elements, "+"+field, "+"+hash.fields[field])
+		}
+	})
+	if err != nil {
+		return errorReply(err)
+	}
+	return arrayReply(elements)
+}
+
+func (s *session) handleHDel(parts []string) string {
+	if len(parts) < 3 {
+		return "-ERR wrong number of arguments for 'HDEL' command"
+	}
+
+	removed := 0
+	err := s.updateHash(parts[1], func(hash *HashValue) error {
+		for _, field := range parts[2:] {
+			if hash.Delete(field) {
+				removed++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errorReply(err)
+	}
+	return fmt.Sprintf(":%d", removed)
+}
+
+func (s *session) handleHLen(parts []string) string {
+	if len(parts) != 2 {
+		return "-ERR wrong number of arguments for 'HLEN' command"
+	}
+
+	length := 0
+	if _, err := s.viewHash(parts[1], func(hash *HashValue) { length = hash.Len() }); err != nil {
+		return errorReply(err)
+	}
+	return fmt.Sprintf(":%d", length)
+}
+
+func (s *session) handleHExists(parts []string) string {
+	if len(parts) != 3 {
+		return "-ERR wrong number of arguments for 'HEXISTS' command"
+	}
+
+	found := false
+	if _, err := s.viewHash(parts[1], func(hash *HashValue) { _, found = hash.Get(parts[2]) }); err != nil {
+		return errorReply(err)
+	}
+	if found {
+		return ":1"
+	}
+	return ":0"
+}
+
+func (s *session) handleHIncrBy(parts []string) string {
+	if len(parts) != 4 {
+		return "-ERR wrong number of arguments for 'HINCRBY' command"
+	}
+
+	delta, err := strconv.ParseInt(parts[3], 10, 64)
+	if err != nil {
+		return "-ERR value is not an integer"
+	}
+
+	var result int64
+	err = s.updateHash(parts[1], func(hash *HashValue) error {
+		value, exists := hash.Get(parts[2])
+		value, n, err := addInt(value, exists, delta)
+		if err != nil {
+			return fmt.Errorf("hash %w", err)
+		}
+		hash.Set(parts[2], value)
+		result = n
+		return nil
+	})
+	if err != nil {
+		return errorReply(err)
+	}
+	return fmt.Sprintf(":%d", result)
+}
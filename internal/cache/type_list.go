@@ -0,0 +1,298 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ListValue is a list of strings, see LPUSH. It is a ring buffer, so pushes
+// and pops at both ends are O(1).
+type ListValue struct {
+	items []string
+	head  int // index of the first element in items
+	len   int
+	bytes int64 // total length of the elements
+}
+
+func (l *ListValue) Type() string { return "list" }
+
+func (l *ListValue) Size() int64 {
+	return l.bytes + int64(len(l.items))*collectionOverhead
+}
+
+func (l *ListValue) Clone() Value {
+	clone := *l
+	clone.items = slices.Clone(l.items)
+	return &clone
+}
+
+func (l *ListValue) Len() int {
+	return l.len
+}
+
+// At returns the element at index i, 0 being the head
+func (l *ListValue) At(i int) string {
+	return l.items[(l.head+i)%len(l.items)]
+}
+
+func (l *ListValue) PushFront(value string) {
+	l.grow()
+	l.head = (l.head - 1 + len(l.items)) % len(l.items)
+	l.items[l.head] = value
+	l.len++
+	l.bytes += int64(len(value))
+}
+
+func (l *ListValue) PushBack(value string) {
+	l.grow()
+	l.items[(l.head+l.len)%len(l.items)] = value
+	l.len++
+	l.bytes += int64(len(value))
+}
+
+// PopFront removes the head, the list must not be empty
+func (l *ListValue) PopFront() string {
+	value := l.At(0)
+	l.items[l.head] = ""
+	l.head = (l.head + 1) % len(l.items)
+	l.len--
+	l.bytes -= int64(len(value))
+	return value
+}
+
+// PopBack removes the tail, the list must not be empty
+func (l *ListValue) PopBack() string {
+	i := (l.head + l.len - 1) % len(l.items)
+	value := l.items[i]
+	l.items[i] = ""
+	l.len--
+	l.bytes -= int64(len(value))
+	return value
+}
+
+// Trim keeps the elements from start to stop, both included and in range
+func (l *ListValue) Trim(start, stop int) {
+	kept := make([]string, 0, max(stop-start+1, 0))
+	l.bytes = 0
+	for i := start; i <= stop; i++ {
+		kept = append(kept, l.At(i))
+		l.bytes += int64(len(l.At(i)))
+	}
+	l.items, l.head, l.len = kept, 0, len(kept)
+}
+
+// grow makes room for one more element
+func (l *ListValue) grow() {
+	if l.len < len(l.items) {
+		return
+	}
+
+	items := make([]string, max(2*len(l.items), 4))
+	for i := 0; i < l.len; i++ {
+		items[i] = l.At(i)
+	}
+	l.items, l.head = items, 0
+}
+
+// listRange converts start and stop as given to LRANGE and LTRIM, negative
+// ones counting from the tail, to indexes within a list of length n. It
+// returns an empty range if nothing is selected.
+func listRange(start, stop, n int) (int, int) {
+	if start < 0 {
+		start = max(n+start, 0)
+	}
+	if stop < 0 {
+		stop = n + stop
+	}
+	stop = min(stop, n-1)
+	if start > stop {
+		return 0, -1
+	}
+	return start, stop
+}
+
+// updateList runs fn on the list at key, created empty if missing, and
+// deletes the key if fn leaves the list empty
+func (s *session) updateList(key string, fn func(list *ListValue) error) error {
+	_, err := s.cache.Update(key, func(value Value, exists bool) (Value, error) {
+		list, ok := value.(*ListValue)
+		if !exists {
+			list = &ListValue{}
+		} else if !ok {
+			return value, ErrWrongType
+		}
+
+		if err := fn(list); err != nil {
+			return value, err
+		}
+		if list.Len() == 0 {
+			return list, RemoveEntry
+		}
+		return list, nil
+	})
+	return err
+}
+
+// viewList runs fn on the list at key and reports whether the key exists
+func (s *session) viewList(key string, fn func(list *ListValue)) (bool, error) {
+	var err error
+	exists := s.cache.View(key, func(value Value) {
+		if list, ok := value.(*ListValue); ok {
+			fn(list)
+		} else {
+			err = ErrWrongType
+		}
+	})
+	return exists, err
+}
+
+// pop removes an element from the head, or the tail if fromTail is set.
+// ok is false if the list does not exist.
+func (s *session) pop(key string, fromTail bool) (value string, ok bool, err error) {
+	err = s.updateList(key, func(list *ListValue) error {
+		if list.Len() == 0 {
+			return nil // new empty list, removed again
+		}
+
+		if fromTail {
+			value = list.PopBack()
+		} else {
+			value = list.PopFront()
+		}
+		ok = true
+		return nil
+	})
+	return value, ok, err
+}
+
+// handlePush handles LPUSH and RPUSH and replies with the new length
+func (s *session) handlePush(parts []string, toTail bool) string {
+	if len(parts) < 3 {
+		return fmt.Sprintf("-ERR wrong number of arguments for '%s' command", strings.ToUpper(parts[0]))
+	}
+
+	length := 0
+	err := s.updateList(parts[1], func(list *ListValue) error {
+		for _, value := range parts[2:] {
+			if toTail {
+				list.PushBack(value)
+			} else {
+				list.PushFront(value)
+			}
+		}
+		length = list.Len()
+		return nil
+	})
+	if err != nil {
+		return errorReply(err)
+	}
+
+	s.blocked.serve(parts[1], s.pop)
+	return fmt.Sprintf(":%d", length)
+}
+
+// handlePop handles LPOP and RPOP
+func (s *session) handlePop(parts []string, fromTail bool) string {
+	if len(parts) != 2 {
+		return fmt.Sprintf("-ERR wrong number of arguments for '%s' command", strings.ToUpper(parts[0]))
+	}
+
+	value, ok, err := s.pop(parts[1], fromTail)
+	switch {
+	case err != nil:
+		return errorReply(err)
+	case !ok:
+		return "-ERR key not found"
+	}
+	return fmt.Sprintf("+%s", value)
+}
+
+// handleBlockingPop handles BLPOP and BRPOP: it pops from the first non-empty
+// list of the given keys, or waits up to timeout seconds (0 for no limit) for
+// a push, and replies with the key and the value, or nil on timeout
+func (s *session) handleBlockingPop(parts []string, fromTail bool) string {
+	if len(parts) < 3 {
+		return fmt.Sprintf("-ERR wrong number of arguments for '%s' command", strings.ToUpper(parts[0]))
+	}
+
+	seconds, err := strconv.ParseFloat(parts[len(parts)-1], 64)
+	if err != nil || seconds != seconds {
+		return "-ERR timeout is not a float or out of range"
+	}
+	if seconds < 0 {
+		return "-ERR timeout is negative"
+	}
+	timeout := time.Duration(seconds * float64(time.Second))
+
+	key, value, err := s.blocked.pop(s.ctx, parts[1:len(parts)-1], fromTail, timeout, s.pop)
+	switch {
+	case errors.Is(err, errTimeout):
+		return nilElement
+	case err != nil:
+		return errorReply(err)
+	}
+	return arrayReply([]string{"+" + key, "+" + value})
+}
+
+func (s *session) handleLRange(parts []string) string {
+	if len(parts) != 4 {
+		return "-ERR wrong number of arguments for 'LRANGE' command"
+	}
+
+	start, err1 := strconv.Atoi(parts[2])
+	stop, err2 := strconv.Atoi(parts[3])
+	if err1 != nil || err2 != nil {
+		return "-ERR value is not an integer"
+	}
+
+	var elements []string
+	_, err := s.viewList(parts[1], func(list *ListValue) {
+		start, stop := listRange(start, stop, list.Len())
+		for i := start; i <= stop; i++ {
+			elements = append(elements, "+"+list.At(i))
+		}
+	})
+	if err != nil {
+		return errorReply(err)
+	}
+	return arrayReply(elements)
+}
+
+func (s *session) handleLLen(parts []string) string {
+	if len(parts) != 2 {
+		return "-ERR wrong number of arguments for 'LLEN' command"
+	}
+
+	length := 0
+	if _, err := s.viewList(parts[1], func(list *ListValue) { length = list.Len() }); err != nil {
+		return errorReply(err)
+	}
+	return fmt.Sprintf(":%d", length)
+}
+
+// handleLTrim keeps only the elements from start to stop
+func (s *session) handleLTrim(parts []string) string {
+	if len(parts) != 4 {
+		return "-ERR wrong number of arguments for 'LTRIM' command"
+	}
+
+	start, err1 := strconv.Atoi(parts[2])
+	stop, err2 := strconv.Atoi(parts[3])
+	if err1 != nil || err2 != nil {
+		return "-ERR value is not an integer"
+	}
+
+	err := s.updateList(parts[1], func(list *ListValue) error {
+		start, stop := listRange(start, stop, list.Len())
+		list.Trim(start, stop)
+		return nil
+	})
+	if err != nil {
+		return errorReply(err)
+	}
+	return "+OK"
+}
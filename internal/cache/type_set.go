@@ -0,0 +1,382 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxRandomMembers bounds the count of SRANDMEMBER with a negative count,
+// which may repeat members as often as asked
+const maxRandomMembers = 1 << 16
+
+// SetValue is a set of distinct strings, see SADD
+type SetValue struct {
+	members map[string]struct{}
+	bytes   int64 // total length of the members
+}
+
+func NewSetValue() *SetValue {
+	return &SetValue{members: make(map[string]struct{})}
+}
+
+func (set *SetValue) Type() string { return "set" }
+
+func (set *SetValue) Size() int64 {
+	return set.bytes + int64(len(set.members))*collectionOverhead
+}
+
+func (set *SetValue) Len() int {
+	return len(set.members)
+}
+
+func (set *SetValue) Contains(member string) bool {
+	_, exists := set.members[member]
+	return exists
+}
+
+// Add adds member and reports whether it is new
+func (set *SetValue) Add(member string) bool {
+	if set.Contains(member) {
+		return false
+	}
+	set.members[member] = struct{}{}
+	set.bytes += int64(len(member))
+	return true
+}
+
+// Remove deletes member and reports whether it existed
+func (set *SetValue) Remove(member string) bool {
+	if !set.Contains(member) {
+		return false
+	}
+	delete(set.members, member)
+	set.bytes -= int64(len(member))
+	return true
+}
+
+// Members returns the members in no particular order
+func (set *SetValue) Members() []string {
+	return slices.Collect(maps.Keys(set.members))
+}
+
+func (set *SetValue) Clone() Value {
+	return set.clone()
+}
+
+func (set *SetValue) clone() *SetValue {
+	return &SetValue{members: maps.Clone(set.members), bytes: set.bytes}
+}
+
+// MarshalJSON encodes the set as a sorted array, for stores
+func (set *SetValue) MarshalJSON() ([]byte, error) {
+	return json.Marshal(slices.Sorted(maps.Keys(set.members)))
+}
+
+// updateSet runs fn on the set at key, created empty if missing, and deletes
+// the key if fn leaves the set empty
+func (s *session) updateSet(key string, fn func(set *SetValue) error) error {
+	_, err := s.cache.Update(key, func(value Value, exists bool) (Value, error) {
+		set, ok := value.(*SetValue)
+		if !exists {
+			set = NewSetValue()
+		} else if !ok {
+			return value, ErrWrongType
+		}
+
+		if err := fn(set); err != nil {
+			return value, err
+		}
+		if set.Len() == 0 {
+			return set, RemoveEntry
+		}
+		return set, nil
+	})
+	return err
+}
+
+// viewSet runs fn on the set at key and reports whether the key exists
+func (s *session) viewSet(key string, fn func(set *SetValue)) (bool, error) {
+	var err error
+	exists := s.cache.View(key, func(value Value) {
+		if set, ok := value.(*SetValue); ok {
+			fn(set)
+		} else {
+			err = ErrWrongType
+		}
+	})
+	return exists, err
+}
+
+// randomMembers returns count distinct members of set in random order, or
+// all of them if the set is smaller
+func randomMembers(set *SetValue, count int) []string {
+	members := set.Members()
+	count = min(count, len(members))
+	for i := 0; i < count; i++ {
+		j := i + rand.IntN(len(members)-i)
+		members[i], members[j] = members[j], members[i]
+	}
+	return members[:count]
+}
+
+// memberReply replies with the members sorted, so replies are stable
+func memberReply(members []string) string {
+	slices.Sort(members)
+	elements := make([]string, len(members))
+	for i, member := range members {
+		elements[i] = "+" + member
+	}
+	return arrayReply(elements)
+}
+
+func (s *session) handleSAdd(parts []string) string {
+	if len(parts) < 3 {
+		return "-ERR wrong number of arguments for 'SADD' command"
+	}
+
+	added := 0
+	err := s.updateSet(parts[1], func(set *SetValue) error {
+		for _, member := range parts[2:] {
+			if set.Add(member) {
+				added++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errorReply(err)
+	}
+	return fmt.Sprintf(":%d", added)
+}
+
+func (s *session) handleSRem(parts []string) string {
+	if len(parts) < 3 {
+		return "-ERR wrong number of arguments for 'SREM' command"
+	}
+
+	removed := 0
+	err := s.updateSet(parts[1], func(set *SetValue) error {
+		for _, member := range parts[2:] {
+			if set.Remove(member) {
+				removed++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errorReply(err)
+	}
+	return fmt.Sprintf(":%d", removed)
+}
+
+func (s *session) handleSIsMember(parts []string) string {
+	if len(parts) != 3 {
+		return "-ERR wrong number of arguments for 'SISMEMBER' command"
+	}
+
+	found := false
+	if _, err := s.viewSet(parts[1], func(set *SetValue) { found = set.Contains(parts[2]) }); err != nil {
+		return errorReply(err)
+	}
+	if found {
+		return ":1"
+	}
+	return ":0"
+}
+
+func (s *session) handleSMembers(parts []string) string {
+	if len(parts) != 2 {
+		return "-ERR wrong number of arguments for 'SMEMBERS' command"
+	}
+
+	var members []string
+	if _, err := s.viewSet(parts[1], func(set *SetValue) { members = set.Members() }); err != nil {
+		return errorReply(err)
+	}
+	return memberReply(members)
+}
+
+func (s *session) handleSCard(parts []string) string {
+	if len(parts) != 2 {
+		return "-ERR wrong number of arguments for 'SCARD' command"
+	}
+
+	length := 0
+	if _, err := s.viewSet(parts[1], func(set *SetValue) { length = set.Len() }); err != nil {
+		return errorReply(err)
+	}
+	return fmt.Sprintf(":%d", length)
+}
+
+// handleSPop removes random members: one, replied as a string, or count of
+// them, replied as an array
+func (s *session) handleSPop(parts []string) string {
+	if len(parts) != 2 && len(parts) != 3 {
+		return "-ERR wrong number of arguments for 'SPOP' command"
+	}
+
+	count := 1
+	if len(parts) == 3 {
+		n, err := strconv.Atoi(parts[2])
+		if err != nil || n < 0 {
+			return "-ERR value is out of range, must be positive"
+		}
+		count = n
+	}
+
+	var popped []string
+	err := s.updateSet(parts[1], func(set *SetValue) error {
+		popped = randomMembers(set, count)
+		for _, member := range popped {
+			set.Remove(member)
+		}
+		return nil
+	})
+	switch {
+	case err != nil:
+		return errorReply(err)
+	case len(parts) == 3:
+		return memberReply(popped)
+	case len(popped) == 0:
+		return "-ERR key not found"
+	}
+	return fmt.Sprintf("+%s", popped[0])
+}
+
+// handleSRandMember returns random members without removing them: one,
+// replied as a string, or count of them, replied as an array. A negative
+// count may return the same member several times, up to maxRandomMembers.
+func (s *session) handleSRandMember(parts []string) string {
+	if len(parts) != 2 && len(parts) != 3 {
+		return "-ERR wrong number of arguments for 'SRANDMEMBER' command"
+	}
+
+	count := 1
+	if len(parts) == 3 {
+		n, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return "-ERR value is not an integer"
+		}
+		if n < -maxRandomMembers {
+			return fmt.Sprintf("-ERR value is out of range, a negative count must be at least -%d", maxRandomMembers)
+		}
+		count = n
+	}
+
+	var members []string
+	_, err := s.viewSet(parts[1], func(set *SetValue) {
+		if count >= 0 {
+			members = randomMembers(set, count)
+			return
+		}
+
+		all := set.Members()
+		for range -count {
+			members = append(members, all[rand.IntN(len(all))])
+		}
+	})
+	switch {
+	case err != nil:
+		return errorReply(err)
+	case len(parts) == 3:
+		elements := make([]string, len(members))
+		for i, member := range members {
+			elements[i] = "+" + member
+		}
+		return arrayReply(elements)
+	case len(members) == 0:
+		return "-ERR key not found"
+	}
+	return fmt.Sprintf("+%s", members[0])
+}
+
+// setOp combines sets for SINTER, SUNION and SDIFF
+type setOp func(sets []*SetValue) *SetValue
+
+func setInter(sets []*SetValue) *SetValue {
+	smallest := slices.MinFunc(sets, func(a, b *SetValue) int { return a.Len() - b.Len() })
+
+	result := NewSetValue()
+	for member := range smallest.members {
+		if !slices.ContainsFunc(sets, func(set *SetValue) bool { return !set.Contains(member) }) {
+			result.Add(member)
+		}
+	}
+	return result
+}
+
+func setUnion(sets []*SetValue) *SetValue {
+	result := NewSetValue()
+	for _, set := range sets {
+		for member := range set.members {
+			result.Add(member)
+		}
+	}
+	return result
+}
+
+// setDiff returns the members of the first set that are in none of the others
+func setDiff(sets []*SetValue) *SetValue {
+	result := sets[0].clone()
+	for _, set := range sets[1:] {
+		for member := range set.members {
+			result.Remove(member)
+		}
+	}
+	return result
+}
+
+// handleSetOp handles SINTER, SUNION and SDIFF, and with store set their
+// STORE variants, which save the result at the first key and reply with its
+// size. Missing keys count as empty sets. The sets are read one at a time,
+// so the result may mix writes made while the command runs.
+func (s *session) handleSetOp(parts []string, op setOp, store bool) string {
+	minArgs := 2
+	if store {
+		minArgs = 3
+	}
+	if len(parts) < minArgs {
+		return fmt.Sprintf("-ERR wrong number of arguments for '%s' command", strings.ToUpper(parts[0]))
+	}
+
+	keys := parts[1:]
+	if store {
+		keys = parts[2:]
+	}
+
+	// the sets are cloned at the same moment, so the result is one that
+	// existed even with concurrent writes
+	sets := make([]*SetValue, len(keys))
+	var err error
+	s.cache.ViewMany(keys, func(i int, value Value, exists bool) {
+		set, ok := value.(*SetValue)
+		switch {
+		case !exists:
+			sets[i] = NewSetValue()
+		case !ok:
+			err = ErrWrongType
+		default:
+			sets[i] = set.clone()
+		}
+	})
+	if err != nil {
+		return errorReply(err)
+	}
+	result := op(sets)
+
+	if !store {
+		return memberReply(result.Members())
+	}
+
+	if result.Len() == 0 {
+		s.cache.Delete(parts[1])
+	} else if err := s.cache.Put(parts[1], result); err != nil {
+		return fmt.Sprintf("-ERR %v", err)
+	}
+	return fmt.Sprintf(":%d", result.Len())
+}
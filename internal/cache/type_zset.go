@@ -0,0 +1,381 @@
+package cache
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// errNaNScore is returned when ZINCRBY adds +inf to -inf
+var errNaNScore = errors.New("resulting score is not a number (NaN)")
+
+// SortedSetValue is a set of members ordered by score, see ZADD. A map gives
+// the score of a member and a skip list keeps the order.
+type SortedSetValue struct {
+	scores map[string]float64
+	list   *skipList
+	bytes  int64 // total length of the members
+}
+
+func NewSortedSetValue() *SortedSetValue {
+	return &SortedSetValue{scores: make(map[string]float64), list: newSkipList()}
+}
+
+func (z *SortedSetValue) Type() string { return "zset" }
+
+// Size counts each member twice for the overhead, as it is in the map and in
+// the skip list
+func (z *SortedSetValue) Size() int64 {
+	return z.bytes + int64(len(z.scores))*(8+2*collectionOverhead)
+}
+
+func (z *SortedSetValue) Clone() Value {
+	clone := NewSortedSetValue()
+	for member, score := range z.scores {
+		clone.Add(member, score)
+	}
+	return clone
+}
+
+func (z *SortedSetValue) Len() int {
+	return len(z.scores)
+}
+
+// Add sets the score of member and reports whether it is new
+func (z *SortedSetValue) Add(member string, score float64) bool {
+	old, exists := z.scores[member]
+	if exists {
+		if old == score {
+			return false
+		}
+		z.list.delete(old, member)
+	} else {
+		z.bytes += int64(len(member))
+	}
+
+	z.scores[member] = score
+	z.list.insert(score, member)
+	return !exists
+}
+
+// Remove deletes member and reports whether it existed
+func (z *SortedSetValue) Remove(member string) bool {
+	score, exists := z.scores[member]
+	if !exists {
+		return false
+	}
+
+	delete(z.scores, member)
+	z.list.delete(score, member)
+	z.bytes -= int64(len(member))
+	return true
+}
+
+func (z *SortedSetValue) Score(member string) (float64, bool) {
+	score, exists := z.scores[member]
+	return score, exists
+}
+
+// Rank returns the 0-based position of member by ascending score
+func (z *SortedSetValue) Rank(member string) (int, bool) {
+	score, exists := z.scores[member]
+	if !exists {
+		return 0, false
+	}
+	return z.list.rank(score, member), true
+}
+
+// updateSortedSet runs fn on the sorted set at key, created empty if missing,
+// and deletes the key if fn leaves the set empty
+func (s *session) updateSortedSet(key string, fn func(zset *SortedSetValue) error) error {
+	_, err := s.cache.Update(key, func(value Value, exists bool) (Value, error) {
+		zset, ok := value.(*SortedSetValue)
+		if !exists {
+			zset = NewSortedSetValue()
+		} else if !ok {
+			return value, ErrWrongType
+		}
+
+		if err := fn(zset); err != nil {
+			return value, err
+		}
+		if zset.Len() == 0 {
+			return zset, RemoveEntry
+		}
+		return zset, nil
+	})
+	return err
+}
+
+// viewSortedSet runs fn on the sorted set at key and reports whether the key exists
+func (s *session) viewSortedSet(key string, fn func(zset *SortedSetValue)) (bool, error) {
+	var err error
+	exists := s.cache.View(key, func(value Value) {
+		if zset, ok := value.(*SortedSetValue); ok {
+			fn(zset)
+		} else {
+			err = ErrWrongType
+		}
+	})
+	return exists, err
+}
+
+// parseScore parses a score, which may be inf but not NaN
+func parseScore(s string) (float64, bool) {
+	score, err := strconv.ParseFloat(s, 64)
+	return score, err == nil && !math.IsNaN(score)
+}
+
+// appendMember adds a member, and its score if withScores, to a reply
+func appendMember(elements []string, node *skipListNode, withScores bool) []string {
+	elements = append(elements, "+"+node.member)
+	if withScores {
+		elements = append(elements, "+"+formatFloat(node.score))
+	}
+	return elements
+}
+
+func (s *session) handleZAdd(parts []string) string {
+	if len(parts) < 4 || len(parts)%2 != 0 {
+		return "-ERR wrong number of arguments for 'ZADD' command"
+	}
+
+	scores := make([]float64, 0, len(parts)/2-1)
+	for i := 2; i < len(parts); i += 2 {
+		score, ok := parseScore(parts[i])
+		if !ok {
+			return "-ERR value is not a valid float"
+		}
+		scores = append(scores, score)
+	}
+
+	added := 0
+	err := s.updateSortedSet(parts[1], func(zset *SortedSetValue) error {
+		for i, score := range scores {
+			if zset.Add(parts[3+2*i], score) {
+				added++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errorReply(err)
+	}
+	return fmt.Sprintf(":%d", added)
+}
+
+func (s *session) handleZRem(parts []string) string {
+	if len(parts) < 3 {
+		return "-ERR wrong number of arguments for 'ZREM' command"
+	}
+
+	removed := 0
+	err := s.updateSortedSet(parts[1], func(zset *SortedSetValue) error {
+		for _, member := range parts[2:] {
+			if zset.Remove(member) {
+				removed++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return errorReply(err)
+	}
+	return fmt.Sprintf(":%d", removed)
+}
+
+func (s *session) handleZScore(parts []string) string {
+	if len(parts) != 3 {
+		return "-ERR wrong number of arguments for 'ZSCORE' command"
+	}
+
+	var score float64
+	var found bool
+	exists, err := s.viewSortedSet(parts[1], func(zset *SortedSetValue) {
+		score, found = zset.Score(parts[2])
+	})
+	switch {
+	case err != nil:
+		return errorReply(err)
+	case !exists:
+		return "-ERR key not found"
+	case !found:
+		return "-ERR member not found"
+	}
+	return fmt.Sprintf("+%s", formatFloat(score))
+}
+
+// handleZIncrBy adds to the score of a member, a missing one starting at 0
+func (s *session) handleZIncrBy(parts []string) string {
+	if len(parts) != 4 {
+		return "-ERR wrong number of arguments for 'ZINCRBY' command"
+	}
+
+	delta, ok := parseScore(parts[2])
+	if !ok {
+		return "-ERR value is not a valid float"
+	}
+
+	var score float64
+	err := s.updateSortedSet(parts[1], func(zset *SortedSetValue) error {
+		old, _ := zset.Score(parts[3])
+		score = old + delta
+		if math.IsNaN(score) {
+			return errNaNScore
+		}
+		zset.Add(parts[3], score)
+		return nil
+	})
+	if err != nil {
+		return errorReply(err)
+	}
+	return fmt.Sprintf("+%s", formatFloat(score))
+}
+
+func (s *session) handleZRank(parts []string) string {
+	if len(parts) != 3 {
+		return "-ERR wrong number of arguments for 'ZRANK' command"
+	}
+
+	var rank int
+	var found bool
+	exists, err := s.viewSortedSet(parts[1], func(zset *SortedSetValue) {
+		rank, found = zset.Rank(parts[2])
+	})
+	switch {
+	case err != nil:
+		return errorReply(err)
+	case !exists:
+		return "-ERR key not found"
+	case !found:
+		return "-ERR member not found"
+	}
+	return fmt.Sprintf(":%d", rank)
+}
+
+func (s *session) handleZCard(parts []string) string {
+	if len(parts) != 2 {
+		return "-ERR wrong number of arguments for 'ZCARD' command"
+	}
+
+	length := 0
+	if _, err := s.viewSortedSet(parts[1], func(zset *SortedSetValue) { length = zset.Len() }); err != nil {
+		return errorReply(err)
+	}
+	return fmt.Sprintf(":%d", length)
+}
+
+// handleZRange handles ZRANGE and, with reverse set, ZREVRANGE, which counts
+// ranks from the highest score
+func (s *session) handleZRange(parts []string, reverse bool) string {
+	withScores := len(parts) == 5 && strings.ToUpper(parts[4]) == "WITHSCORES"
+	if len(parts) != 4 && !withScores {
+		return fmt.Sprintf("-ERR wrong number of arguments for '%s' command", strings.ToUpper(parts[0]))
+	}
+
+	start, err1 := strconv.Atoi(parts[2])
+	stop, err2 := strconv.Atoi(parts[3])
+	if err1 != nil || err2 != nil {
+		return "-ERR value is not an integer"
+	}
+
+	var elements []string
+	_, err := s.viewSortedSet(parts[1], func(zset *SortedSetValue) {
+		n := zset.Len()
+		start, stop := listRange(start, stop, n)
+		if start > stop {
+			return
+		}
+
+		if !reverse {
+			node := zset.list.byRank(start)
+			for i := start; i <= stop; i++ {
+				elements = appendMember(elements, node, withScores)
+				node = node.levels[0].forward
+			}
+			return
+		}
+
+		node := zset.list.byRank(n - 1 - start)
+		for i := start; i <= stop; i++ {
+			elements = appendMember(elements, node, withScores)
+			node = node.backward
+		}
+	})
+	if err != nil {
+		return errorReply(err)
+	}
+	return arrayReply(elements)
+}
+
+// handleZRangeByScore replies with the members with a score between min and
+// max, both included unless prefixed by "(". -inf and +inf are unbounded.
+// LIMIT offset count pages through the result.
+func (s *session) handleZRangeByScore(parts []string) string {
+	if len(parts) < 4 {
+		return "-ERR wrong number of arguments for 'ZRANGEBYSCORE' command"
+	}
+
+	minScore, minExclusive, ok1 := parseScoreBound(parts[2])
+	maxScore, maxExclusive, ok2 := parseScoreBound(parts[3])
+	if !ok1 || !ok2 {
+		return "-ERR min or max is not a float"
+	}
+
+	withScores := false
+	offset, count := 0, -1
+	for i := 4; i < len(parts); i++ {
+		switch strings.ToUpper(parts[i]) {
+		case "WITHSCORES":
+			withScores = true
+		case "LIMIT":
+			if i+2 >= len(parts) {
+				return "-ERR syntax error"
+			}
+			var err1, err2 error
+			offset, err1 = strconv.Atoi(parts[i+1])
+			count, err2 = strconv.Atoi(parts[i+2])
+			if err1 != nil || err2 != nil {
+				return "-ERR value is not an integer"
+			}
+			i += 2
+		default:
+			return "-ERR syntax error"
+		}
+	}
+
+	var elements []string
+	_, err := s.viewSortedSet(parts[1], func(zset *SortedSetValue) {
+		if offset < 0 {
+			return
+		}
+
+		node := zset.list.firstFrom(minScore, minExclusive)
+		for ; node != nil && count != 0; node = node.levels[0].forward {
+			if node.score > maxScore || maxExclusive && node.score == maxScore {
+				break
+			}
+			if offset > 0 {
+				offset--
+				continue
+			}
+			elements = appendMember(elements, node, withScores)
+			count--
+		}
+	})
+	if err != nil {
+		return errorReply(err)
+	}
+	return arrayReply(elements)
+}
+
+// parseScoreBound parses a ZRANGEBYSCORE bound, exclusive if prefixed by "("
+func parseScoreBound(s string) (score float64, exclusive bool, ok bool) {
+	if strings.HasPrefix(s, "(") {
+		s, exclusive = s[1:], true
+	}
+	score, ok = parseScore(s)
+	return score, exclusive, ok
+}
@@ -0,0 +1,30 @@
+package cache
+
+import "errors"
+
+// ErrWrongType is returned by server commands used on a key of another type
+var ErrWrongType = errors.New("WRONGTYPE Operation against a key holding the wrong kind of value")
+
+// Value is what the server stores under a key: a string or a collection.
+// Collections are modified in place under the cache lock with Update and
+// read with View. On a cache with a store, Update changes a Clone instead,
+// as the store may still be writing the stored value.
+type Value interface {
+	// Type returns the name reported by the TYPE command
+	Type() string
+	Sizer
+	Cloner[Value]
+}
+
+// StringValue is the value set by SET
+type StringValue string
+
+func (v StringValue) Type() string { return "string" }
+
+func (v StringValue) Size() int64 { return int64(len(v)) }
+
+func (v StringValue) Clone() Value { return v }
+
+// collectionOverhead roughly accounts for the per-element bookkeeping of a
+// collection: map slot or slice header, and string headers
+const collectionOverhead = 48
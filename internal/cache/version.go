@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrVersionConflict is returned by CompareAndSwap when the entry was written
+// since the caller read its version
+var ErrVersionConflict = errors.New("version conflict")
+
+// GetWithVersion is Get that also returns the version of the entry. Versions
+// come from one counter per cache, so a key deleted and stored again never
+// gets a version it had before.
+func (lru *LRUCache[K, V]) GetWithVersion(key K) (V, uint64, bool) {
+	lru.mu.Lock()
+	defer lru.unlock()
+
+	now := time.Now()
+	node := lru.lookup(key, now)
+	if lru.admission != nil {
+		lru.admission.recordRead(key, node != nil)
+	}
+
+	if node != nil {
+		lru.stats.hits.Add(1)
+		lru.access(node, now)
+		lru.refreshIfStale(node, now)
+		return node.value, node.version, true
+	}
+
+	lru.stats.misses.Add(1)
+	var zero V
+	return zero, 0, false
+}
+
+// CompareAndSwap stores value only if the entry still has the version returned
+// by GetWithVersion, and returns the new version. It fails with ErrNotFound if
+// the key is missing and with ErrVersionConflict if it was written meanwhile,
+// including while a write-through store write was in flight. The expiration
+// of the entry is kept.
+func (lru *LRUCache[K, V]) CompareAndSwap(key K, version uint64, value V) (uint64, error) {
+	return lru.update(key, func(node *DoublyNode[K, V]) (V, error) {
+		switch {
+		case node == nil:
+			return value, ErrNotFound
+		case node.version != version:
+			return value, ErrVersionConflict
+		}
+		return value, nil
+	})
+}
+
+// update atomically replaces the value of key with the one fn computes from
+// the current entry, nil if key is missing, and returns the new version. The
+// expiration, tags and soft TTL of an existing entry are kept. If fn returns
+// RemoveEntry the entry is deleted instead. Any other error from fn is
+// returned and nothing is written.
+//
+// With a store, the new value is written like a Put. In write-through mode
+// writeMu is held from fn until the cache is updated, so no other store write
+// can happen in between while the store is written without holding lru.mu.
+// Writes that skip the store, such as loads and refreshes, can still happen
+// then: if the entry was written meanwhile, the cache keeps that write and
+// update fails with ErrVersionConflict.
+func (lru *LRUCache[K, V]) update(key K, fn func(node *DoublyNode[K, V]) (V, error)) (uint64, error) {
+	queued := lru.queue != nil && lru.queue.reserve()
+	writeThrough := lru.store != nil && !queued
+	if writeThrough {
+		lru.writeMu.Lock()
+	}
+
+	lru.mu.Lock()
+	now := time.Now()
+	node := lru.lookup(key, now)
+	version := nodeVersion(node)
+
+	value, err := fn(node)
+	remove := err == RemoveEntry
+	if remove {
+		err = nil
+	}
+
+	size := lru.weigher(key, value)
+	if err == nil && !remove && lru.maxBytes > 0 && size > lru.maxBytes {
+		err = ErrEntryTooLarge
+	}
+
+	var meta entryMeta
+	if node != nil {
+		meta = entryMeta{expiresAt: node.expiresAt, tags: node.tags, refresh: node.refresh}
+	}
+
+	switch {
+	case err != nil:
+		if queued {
+			lru.queue.release()
+		}
+		if writeThrough {
+			lru.writeMu.Unlock()
+		}
+		lru.unlock()
+		return 0, err
+
+	case writeThrough:
+		lru.unlock()
+		if remove {
+			err = lru.store.Delete(context.Background(), key)
+		} else {
+			err = lru.store.Save(context.Background(), key, value)
+		}
+		if err != nil {
+			lru.writeMu.Unlock()
+			return 0, err
+		}
+		lru.mu.Lock()
+		lru.writeMu.Unlock()
+
+	case queued:
+		lru.queue.add(key, value, remove)
+	}
+	defer lru.unlock()
+
+	now = time.Now()
+	node = lru.lookup(key, now)
+	if writeThrough && nodeVersion(node) != version {
+		return 0, ErrVersionConflict
+	}
+	if remove {
+		lru.staleLoad(key)
+		if node != nil {
+			lru.removeNode(node, ReasonDeleted)
+		}
+		return 0, nil
+	}
+	return lru.set(key, value, size, meta, now)
+}
+
+// nodeVersion returns the version of node, 0 if it is nil
+func nodeVersion[K comparable, V any](node *DoublyNode[K, V]) uint64 {
+	if node == nil {
+		return 0
+	}
+	return node.version
+}
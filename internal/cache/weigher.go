@@ -0,0 +1,39 @@
+package cache
+
+import "unsafe"
+
+// Weigher returns the cost in bytes of an entry when the cache is
+// bounded by memory rather than by item count
+type Weigher[K comparable, V any] func(key K, value V) int64
+
+// Sizer is implemented by values that know their approximate size in bytes,
+// such as collections. The default weigher uses it.
+type Sizer interface {
+	Size() int64
+}
+
+// defaultWeigher counts key and value length plus the per-node overhead.
+// Strings and byte slices count their length, Sizers their Size, other types
+// their shallow size.
+func defaultWeigher[K comparable, V any]() Weigher[K, V] {
+	// node struct plus roughly one map bucket slot (key and pointer)
+	var node DoublyNode[K, V]
+	overhead := int64(unsafe.Sizeof(node)) + int64(unsafe.Sizeof(node.key)) + int64(unsafe.Sizeof(&node))
+
+	return func(key K, value V) int64 {
+		return overhead + sizeOf(key) + sizeOf(value)
+	}
+}
+
+func sizeOf[T any](v T) int64 {
+	switch x := any(v).(type) {
+	case string:
+		return int64(len(x))
+	case []byte:
+		return int64(len(x))
+	case Sizer:
+		return x.Size()
+	default:
+		return 0 // already part of the node size
+	}
+}
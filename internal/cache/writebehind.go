@@ -0,0 +1,187 @@
+package cache
+
+import "sync"
+
+// pendingWrite is a write-behind write not yet in the store
+type pendingWrite[K comparable, V any] struct {
+	key      K
+	value    V
+	deleted  bool
+	attempts int   // failed store writes so far
+	err      error // error of the last attempt
+}
+
+// writeQueue holds the dirty keys of a write-behind cache. Writes to a key
+// that is already queued replace the queued write, so each key is written
+// once per flush with its latest value. The queue is bounded: writers reserve
+// a slot first and block while the queue is full.
+type writeQueue[K comparable, V any] struct {
+	mu       sync.Mutex
+	cond     *sync.Cond // broadcast when writes are added or leave the queue
+	writes   map[K]*pendingWrite[K, V]
+	order    []K                       // queued keys, oldest first
+	inFlight map[K]*pendingWrite[K, V] // taken by a flush, not written yet
+	size     int
+	reserved int
+	closed   bool
+
+	batchSize int
+	kick      chan struct{} // wakes up the flusher
+	flushMu   sync.Mutex    // one flush at a time, so writes of a key stay in order
+}
+
+func newWriteQueue[K comparable, V any](size, batchSize int) *writeQueue[K, V] {
+	q := &writeQueue[K, V]{
+		writes:    make(map[K]*pendingWrite[K, V]),
+		inFlight:  make(map[K]*pendingWrite[K, V]),
+		size:      size,
+		batchSize: batchSize,
+		kick:      make(chan struct{}, 1),
+	}
+	q.cond = sync.NewCond(&q.mu)
+	return q
+}
+
+// reserve waits for a free slot and reports false if the queue is closed
+func (q *writeQueue[K, V]) reserve() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for !q.closed && len(q.writes)+q.reserved >= q.size {
+		q.flushSoon()
+		q.cond.Wait()
+	}
+	if q.closed {
+		return false
+	}
+	q.reserved++
+	return true
+}
+
+// add queues a write using a slot taken by reserve
+func (q *writeQueue[K, V]) add(key K, value V, deleted bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.reserved--
+	if w, exists := q.writes[key]; exists {
+		w.value, w.deleted, w.attempts = value, deleted, 0
+	} else {
+		q.writes[key] = &pendingWrite[K, V]{key: key, value: value, deleted: deleted}
+		q.order = append(q.order, key)
+	}
+	q.cond.Broadcast()
+
+	if len(q.writes) >= q.batchSize {
+		q.flushSoon()
+	}
+}
+
+// release gives back a slot taken by reserve without queueing a write
+func (q *writeQueue[K, V]) release() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.reserved--
+	q.cond.Broadcast()
+}
+
+// flushSoon wakes up the flusher without waiting for it
+func (q *writeQueue[K, V]) flushSoon() {
+	select {
+	case q.kick <- struct{}{}:
+	default:
+	}
+}
+
+// isDirty reports whether key has a write that is not in the store yet
+func (q *writeQueue[K, V]) isDirty(key K) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	_, queued := q.writes[key]
+	_, inFlight := q.inFlight[key]
+	return queued || inFlight
+}
+
+// pending returns the latest write of key that is not in the store yet
+func (q *writeQueue[K, V]) pending(key K) (pendingWrite[K, V], bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if w, ok := q.writes[key]; ok {
+		return *w, true
+	}
+	if w, ok := q.inFlight[key]; ok {
+		return *w, true
+	}
+	return pendingWrite[K, V]{}, false
+}
+
+func (q *writeQueue[K, V]) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.writes)
+}
+
+// take removes up to n of the oldest writes from the queue for a flush
+func (q *writeQueue[K, V]) take(n int) []*pendingWrite[K, V] {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	n = min(n, len(q.order))
+	batch := make([]*pendingWrite[K, V], n)
+	for i, key := range q.order[:n] {
+		batch[i] = q.writes[key]
+		delete(q.writes, key)
+		q.inFlight[key] = batch[i]
+	}
+	q.order = q.order[n:]
+	q.cond.Broadcast()
+	return batch
+}
+
+// done finishes a flushed batch. Failed writes go back to the queue unless
+// the key was written again meanwhile or they failed more than maxRetries
+// times, in which case they are returned as given up.
+func (q *writeQueue[K, V]) done(batch []*pendingWrite[K, V], maxRetries int) (failed []*pendingWrite[K, V]) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, w := range batch {
+		delete(q.inFlight, w.key)
+		if w.err == nil {
+			continue
+		}
+
+		if _, rewritten := q.writes[w.key]; rewritten {
+			continue
+		}
+		w.attempts++
+		if w.attempts > maxRetries {
+			failed = append(failed, w)
+			continue
+		}
+		q.writes[w.key] = w
+		q.order = append(q.order, w.key)
+	}
+	return failed
+}
+
+// closeIfIdle closes the queue if it is empty and no writer holds a slot.
+// Writers then write to the store directly.
+func (q *writeQueue[K, V]) closeIfIdle() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.writes) == 0 && q.reserved > 0 {
+		q.cond.Wait()
+	}
+	if len(q.writes) > 0 {
+		return false
+	}
+	q.closed = true
+	q.cond.Broadcast()
+	return true
+}
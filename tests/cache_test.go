@@ -1,8 +1,16 @@
 package tests
 
 import (
+	"cmp"
+	"context"
+	"errors"
 	"fmt"
+	"maps"
+	"math/rand"
+	"slices"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -210,6 +218,1627 @@ func TestCapacityOne(t *testing.T) {
 	}
 }
 
+func TestTTLExpiry(t *testing.T) {
+	cache := cache.NewLRUCache(3)
+	defer cache.Close()
+
+	cache.PutWithTTL("session", "abc", 50*time.Millisecond)
+	cache.Put("forever", "1")
+
+	if val, ok := cache.Get("session"); !ok || val != "abc" {
+		t.Errorf("Expected 'session':'abc' before expiry, got '%s':%t", val, ok)
+	}
+
+	time.Sleep(80 * time.Millisecond)
+
+	if _, ok := cache.Get("session"); ok {
+		t.Error("Expected 'session' to be expired")
+	}
+	if cache.Contains("session") {
+		t.Error("Expected Contains to report expired key as missing")
+	}
+	if _, ok := cache.Get("forever"); !ok {
+		t.Error("Expected 'forever' to exist")
+	}
+
+	// Put without TTL clears a previous expiration
+	cache.PutWithTTL("a", "1", 50*time.Millisecond)
+	cache.Put("a", "2")
+	time.Sleep(80 * time.Millisecond)
+	if val, ok := cache.Get("a"); !ok || val != "2" {
+		t.Errorf("Expected 'a':'2' to persist, got '%s':%t", val, ok)
+	}
+}
+
+func TestSizeExcludesExpired(t *testing.T) {
+	cache := cache.NewLRUCache(3)
+	defer cache.Close()
+
+	cache.PutWithTTL("a", "1", 30*time.Millisecond)
+	cache.PutWithTTL("b", "2", 30*time.Millisecond)
+	cache.Put("c", "3")
+
+	if cache.Size() != 3 {
+		t.Errorf("Expected size 3, got %d", cache.Size())
+	}
+
+	time.Sleep(50 * time.Millisecond)
+
+	if cache.Size() != 1 {
+		t.Errorf("Expected size 1 after expiry, got %d", cache.Size())
+	}
+
+	// expired entries free their slots instead of evicting live ones
+	cache.PutWithTTL("d", "4", 30*time.Millisecond)
+	cache.Put("e", "5")
+	time.Sleep(50 * time.Millisecond)
+	cache.Put("f", "6")
+	cache.Put("g", "7")
+
+	// 'd' made room for 'f', then 'c' was the LRU victim for 'g'
+	if _, ok := cache.Get("c"); ok {
+		t.Error("Expected 'c' to be evicted")
+	}
+	for _, key := range []string{"e", "f", "g"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("Expected '%s' to exist", key)
+		}
+	}
+}
+
+func TestSweeper(t *testing.T) {
+	lru := cache.NewWithOptions(cache.Options[string, string]{Capacity: 10, SweepInterval: 10 * time.Millisecond})
+	defer lru.Close()
+
+	var expired atomic.Int32
+	lru.OnRemoval(func(key, value string, reason cache.RemovalReason) {
+		if reason == cache.ReasonExpired {
+			expired.Add(1)
+		}
+	})
+	lru.PutWithTTL("a", "1", 20*time.Millisecond)
+	lru.PutWithTTL("b", "2", 20*time.Millisecond)
+	lru.Put("c", "3")
+
+	// no reads, only the sweeper removes the expired entries
+	waitFor(t, func() bool { return lru.Stats().Expirations == 2 })
+	waitFor(t, func() bool { return expired.Load() == 2 })
+	if val, ok := lru.Peek("c"); !ok || val != "3" {
+		t.Errorf("Expected 'c' to stay, got '%s':%t", val, ok)
+	}
+}
+
+func TestGenericCache(t *testing.T) {
+	type user struct {
+		Name string
+		Age  int
+	}
+
+	users := cache.New[int64, user](2)
+	users.Put(1, user{"John", 30})
+	users.Put(2, user{"Jane", 25})
+
+	if u, ok := users.Get(1); !ok || u.Name != "John" || u.Age != 30 {
+		t.Errorf("Expected user 1 to be John/30, got %+v:%t", u, ok)
+	}
+
+	users.Put(3, user{"Jim", 40}) // 2 is the LRU entry now
+
+	if u, ok := users.Get(2); ok {
+		t.Errorf("Expected user 2 to be evicted, got %+v", u)
+	}
+	if u, ok := users.Get(42); ok || u != (user{}) {
+		t.Errorf("Expected zero value for missing key, got %+v:%t", u, ok)
+	}
+}
+
+func TestMemoryBounded(t *testing.T) {
+	lru := cache.NewWithOptions(cache.Options[string, string]{
+		MaxBytes: 10,
+		Weigher:  func(key, value string) int64 { return int64(len(value)) },
+	})
+
+	lru.Put("a", "12345")
+	lru.Put("b", "12345")
+
+	if lru.UsedBytes() != 10 {
+		t.Errorf("Expected 10 used bytes, got %d", lru.UsedBytes())
+	}
+
+	lru.Put("c", "123") // evicts 'a' to fit
+
+	if _, ok := lru.Get("a"); ok {
+		t.Error("Expected 'a' to be evicted")
+	}
+	if lru.Size() != 2 || lru.UsedBytes() != 8 {
+		t.Errorf("Expected 2 items and 8 bytes, got %d and %d", lru.Size(), lru.UsedBytes())
+	}
+
+	// growing an existing entry evicts others, never the entry itself
+	lru.Put("c", "1234567890")
+	if val, ok := lru.Get("c"); !ok || val != "1234567890" || lru.Size() != 1 {
+		t.Errorf("Expected only 'c' to remain, got '%s':%t size %d", val, ok, lru.Size())
+	}
+
+	if err := lru.Put("big", "12345678901"); err != cache.ErrEntryTooLarge {
+		t.Errorf("Expected ErrEntryTooLarge, got %v", err)
+	}
+	if _, ok := lru.Get("c"); !ok {
+		t.Error("Expected rejected entry to leave the cache untouched")
+	}
+
+	// default weigher counts key, value and node overhead
+	sized := cache.NewWithOptions(cache.Options[string, string]{MaxBytes: 1 << 20})
+	sized.Put("key", "value")
+	if used := sized.UsedBytes(); used <= int64(len("key")+len("value")) {
+		t.Errorf("Expected default weight to include node overhead, got %d", used)
+	}
+	sized.Delete("key")
+	if used := sized.UsedBytes(); used != 0 {
+		t.Errorf("Expected 0 used bytes after delete, got %d", used)
+	}
+}
+
+func TestShardedCache(t *testing.T) {
+	sharded := cache.NewSharded[string, string](4, 100)
+	defer sharded.Close()
+
+	for i := 0; i < 50; i++ {
+		sharded.Put(fmt.Sprintf("key_%d", i), fmt.Sprintf("value_%d", i))
+	}
+
+	if sharded.Size() != 50 {
+		t.Errorf("Expected size 50, got %d", sharded.Size())
+	}
+
+	if val, ok := sharded.Get("key_7"); !ok || val != "value_7" {
+		t.Errorf("Expected 'key_7':'value_7', got '%s':%t", val, ok)
+	}
+
+	if !sharded.Delete("key_7") || sharded.Contains("key_7") {
+		t.Error("Expected 'key_7' to be deleted")
+	}
+	if sharded.Size() != 49 {
+		t.Errorf("Expected size 49, got %d", sharded.Size())
+	}
+
+	// never holds more than the per-shard capacity in total
+	for i := 0; i < 1000; i++ {
+		sharded.Put(fmt.Sprintf("more_%d", i), "x")
+	}
+	if sharded.Size() > 100 {
+		t.Errorf("Expected at most 100 items, got %d", sharded.Size())
+	}
+
+	sharded.Clear()
+	if sharded.Size() != 0 {
+		t.Errorf("Expected size 0 after clear, got %d", sharded.Size())
+	}
+}
+
+func TestShardedConcurrency(t *testing.T) {
+	sharded := cache.NewSharded[int, int](cache.DefaultShardCount, 10000)
+	var wg sync.WaitGroup
+
+	numWorkers := 10
+	numOps := 100
+
+	wg.Add(numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		go func(workerID int) {
+			defer wg.Done()
+			for j := 0; j < numOps; j++ {
+				key := workerID*numOps + j
+				sharded.Put(key, j)
+				sharded.Get(key)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if sharded.Size() != numWorkers*numOps {
+		t.Errorf("Expected size %d, got %d", numWorkers*numOps, sharded.Size())
+	}
+}
+
+func newPolicyCache(policy string, capacity int) *cache.LRUCache[string, string] {
+	return cache.NewWithOptions(cache.Options[string, string]{Capacity: capacity, Policy: policy})
+}
+
+func TestFIFOPolicy(t *testing.T) {
+	fifo := newPolicyCache(cache.PolicyFIFO, 2)
+
+	fifo.Put("a", "1")
+	fifo.Put("b", "2")
+	fifo.Get("a") // reads do not protect 'a' from eviction
+	fifo.Put("c", "3")
+
+	if _, ok := fifo.Get("a"); ok {
+		t.Error("Expected oldest entry 'a' to be evicted")
+	}
+	if _, ok := fifo.Get("b"); !ok {
+		t.Error("Expected 'b' to exist")
+	}
+}
+
+func TestLFUPolicy(t *testing.T) {
+	lfu := newPolicyCache(cache.PolicyLFU, 2)
+
+	lfu.Put("a", "1")
+	lfu.Put("b", "2")
+	lfu.Get("a")
+	lfu.Get("a")
+	lfu.Get("b")
+	lfu.Put("c", "3") // 'b' is the least frequently used
+
+	if _, ok := lfu.Get("b"); ok {
+		t.Error("Expected 'b' to be evicted")
+	}
+	if _, ok := lfu.Get("a"); !ok {
+		t.Error("Expected frequently used 'a' to exist")
+	}
+
+	lfu.Put("d", "4") // 'c' has the lowest frequency
+	if _, ok := lfu.Get("c"); ok {
+		t.Error("Expected 'c' to be evicted")
+	}
+}
+
+// a key requested repeatedly must survive a scan of one-off keys with the
+// scan resistant policies, and is lost with the others
+func TestPolicyScanResistance(t *testing.T) {
+	tests := []struct {
+		policy  string
+		survive bool
+	}{
+		{cache.PolicyLRU, false},
+		{cache.PolicyFIFO, false},
+		{cache.Policy2Q, true},
+		{cache.PolicyARC, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			c := newPolicyCache(tt.policy, 4)
+
+			c.Put("hot", "v")
+			c.Get("hot")
+			for _, key := range []string{"a", "b", "c", "d"} {
+				c.Put(key, "v")
+			}
+			// requested again, 2Q has evicted it by now but remembers the key
+			if _, ok := c.Get("hot"); !ok {
+				c.Put("hot", "v")
+			}
+
+			for i := 0; i < 20; i++ {
+				c.Put(fmt.Sprintf("scan_%d", i), "v")
+			}
+
+			if _, ok := c.Get("hot"); ok != tt.survive {
+				t.Errorf("Expected 'hot' present=%t after scan, got %t", tt.survive, ok)
+			}
+			if c.Size() != 4 {
+				t.Errorf("Expected size 4, got %d", c.Size())
+			}
+		})
+	}
+}
+
+func TestAdmissionFilter(t *testing.T) {
+	lru := cache.NewWithOptions(cache.Options[string, string]{Capacity: 2, Admission: true})
+
+	for i := 0; i < 5; i++ {
+		for _, key := range []string{"a", "b"} {
+			if _, ok := lru.Get(key); !ok {
+				lru.Put(key, "hot")
+			}
+		}
+	}
+
+	// one-off keys must not displace the hot set
+	for i := 0; i < 100; i++ {
+		lru.Put(fmt.Sprintf("scan_%d", i), "cold")
+	}
+
+	for _, key := range []string{"a", "b"} {
+		if _, ok := lru.Get(key); !ok {
+			t.Errorf("Expected hot key '%s' to survive the scan", key)
+		}
+	}
+
+	// a key that becomes popular is admitted
+	for i := 0; i < 10; i++ {
+		lru.Get("c")
+	}
+	lru.Put("c", "new")
+	if _, ok := lru.Get("c"); !ok {
+		t.Error("Expected popular key 'c' to be admitted")
+	}
+
+	accepted, rejected := lru.AdmissionStats()
+	if accepted != 1 || rejected != 100 {
+		t.Errorf("Expected 1 accepted and 100 rejected, got %d and %d", accepted, rejected)
+	}
+
+	if accepted, rejected := cache.NewLRUCache(1).AdmissionStats(); accepted != 0 || rejected != 0 {
+		t.Error("Expected no admission stats with admission disabled")
+	}
+}
+
+func TestRemovalListener(t *testing.T) {
+	lru := cache.NewLRUCache(2)
+	defer lru.Close()
+
+	var events []string
+	lru.OnRemoval(func(key, value string, reason cache.RemovalReason) {
+		events = append(events, fmt.Sprintf("%s=%s:%s", key, value, reason))
+	})
+
+	lru.Put("a", "1")
+	lru.Put("a", "2") // replaced
+	lru.Put("b", "1")
+	lru.Put("c", "1") // evicts a
+	lru.Delete("b")   // deleted
+	lru.PutWithTTL("d", "1", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	lru.Get("d") // expired
+	lru.Put("e", "1")
+	lru.Get("c") // c is now the most recent
+	lru.Clear()  // cleared, coldest first
+
+	expected := []string{
+		"a=1:replaced",
+		"a=2:evicted",
+		"b=1:deleted",
+		"d=1:expired",
+		"e=1:cleared",
+		"c=1:cleared",
+	}
+	if fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("Expected events %v, got %v", expected, events)
+	}
+}
+
+func TestRemovalListenerReentrant(t *testing.T) {
+	lru := cache.NewLRUCache(1)
+
+	// listeners run outside the lock, so calling back into the cache must not
+	// deadlock. Re-inserting the evicted key evicts again, those notifications
+	// are delivered after the current one.
+	var events []string
+	lru.OnRemoval(func(key, value string, reason cache.RemovalReason) {
+		events = append(events, key+":"+reason.String())
+		if key == "a" && reason == cache.ReasonEvicted {
+			lru.Put("archived_a", value)
+		}
+	})
+
+	lru.Put("a", "1")
+	lru.Put("b", "2")
+
+	expected := []string{"a:evicted", "b:evicted"}
+	if fmt.Sprint(events) != fmt.Sprint(expected) {
+		t.Errorf("Expected events %v, got %v", expected, events)
+	}
+	if val, ok := lru.Get("archived_a"); !ok || val != "1" {
+		t.Errorf("Expected 'archived_a':'1', got '%s':%t", val, ok)
+	}
+}
+
+func TestStats(t *testing.T) {
+	lru := cache.NewLRUCache(2)
+
+	lru.Put("a", "1")
+	lru.Put("b", "2")
+	lru.Get("a")
+	lru.Get("a")
+	lru.Get("x")
+	lru.Put("c", "3") // evicts b
+	lru.Delete("a")
+	lru.Delete("missing")
+	lru.PutWithTTL("d", "4", time.Millisecond)
+	time.Sleep(5 * time.Millisecond)
+	lru.Get("d")
+
+	expected := cache.Stats{Hits: 2, Misses: 2, Sets: 4, Deletes: 1, Evictions: 1, Expirations: 1}
+	stats := lru.Stats()
+	if stats != expected {
+		t.Errorf("Expected %+v, got %+v", expected, stats)
+	}
+	if stats.HitRatio() != 0.5 {
+		t.Errorf("Expected hit ratio 0.5, got %f", stats.HitRatio())
+	}
+
+	lru.ResetStats()
+	if stats := lru.Stats(); stats != (cache.Stats{}) || stats.HitRatio() != 0 {
+		t.Errorf("Expected zero stats after reset, got %+v", stats)
+	}
+}
+
+func TestGetOrLoad(t *testing.T) {
+	lru := cache.NewLRUCache(10)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (string, error) {
+		calls.Add(1)
+		<-release
+		return "loaded_" + key, nil
+	}
+
+	var wg sync.WaitGroup
+	results := make([]string, 50)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			val, err := lru.GetOrLoad(context.Background(), "a", loader)
+			if err != nil {
+				t.Errorf("GetOrLoad failed: %v", err)
+			}
+			results[i] = val
+		}(i)
+	}
+
+	// let the callers pile up on the in-flight load
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Errorf("Expected 1 loader call, got %d", calls.Load())
+	}
+	for _, val := range results {
+		if val != "loaded_a" {
+			t.Fatalf("Expected 'loaded_a', got '%s'", val)
+		}
+	}
+	if val, ok := lru.Get("a"); !ok || val != "loaded_a" {
+		t.Errorf("Expected loaded value to be cached, got '%s':%t", val, ok)
+	}
+}
+
+func TestGetOrLoadError(t *testing.T) {
+	lru := cache.NewWithOptions(cache.Options[string, string]{Capacity: 10, NegativeTTL: 50 * time.Millisecond})
+
+	errNotFound := errors.New("not found")
+	var calls atomic.Int32
+	loader := func(ctx context.Context, key string) (string, error) {
+		calls.Add(1)
+		return "", errNotFound
+	}
+
+	for i := 0; i < 3; i++ {
+		if _, err := lru.GetOrLoad(context.Background(), "a", loader); !errors.Is(err, errNotFound) {
+			t.Errorf("Expected errNotFound, got %v", err)
+		}
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected the error to be cached, got %d loader calls", calls.Load())
+	}
+	if lru.Contains("a") {
+		t.Error("Expected failed load not to store a value")
+	}
+
+	time.Sleep(80 * time.Millisecond)
+
+	lru.GetOrLoad(context.Background(), "a", loader)
+	if calls.Load() != 2 {
+		t.Errorf("Expected a new loader call after NegativeTTL, got %d calls", calls.Load())
+	}
+}
+
+func TestGetOrLoadWrittenMeanwhile(t *testing.T) {
+	lru := cache.NewLRUCache(10)
+
+	release := make(chan struct{})
+	loader := func(ctx context.Context, key string) (string, error) {
+		<-release
+		return "stale-from-db", nil
+	}
+	load := func(key string) chan string {
+		result := make(chan string, 1)
+		go func() {
+			val, _ := lru.GetOrLoad(context.Background(), key, loader)
+			result <- val
+		}()
+		return result
+	}
+
+	written, deleted := load("written"), load("deleted")
+	time.Sleep(10 * time.Millisecond)
+	lru.Put("written", "fresh")
+	lru.Delete("deleted")
+	close(release)
+
+	// the callers get the loaded value, the cache keeps the newer writes
+	if val := <-written; val != "stale-from-db" {
+		t.Errorf("Expected the caller to get the loaded value, got '%s'", val)
+	}
+	<-deleted
+	if val, _ := lru.Peek("written"); val != "fresh" {
+		t.Errorf("Expected the Put during the load to stay, got '%s'", val)
+	}
+	if _, ok := lru.Peek("deleted"); ok {
+		t.Error("Expected the key deleted during the load to stay deleted")
+	}
+
+	// a later miss loads again
+	if val, _ := lru.GetOrLoad(context.Background(), "deleted", loader); val != "stale-from-db" {
+		t.Errorf("Expected a new load, got '%s'", val)
+	}
+	if _, ok := lru.Peek("deleted"); !ok {
+		t.Error("Expected the new load to be stored")
+	}
+}
+
+func TestGetOrLoadCancel(t *testing.T) {
+	lru := cache.NewLRUCache(10)
+
+	release := make(chan struct{})
+	loadCtxErr := make(chan error, 1)
+	loader := func(ctx context.Context, key string) (string, error) {
+		<-release
+		loadCtxErr <- ctx.Err()
+		return "value", nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	first := make(chan error, 1)
+	go func() {
+		_, err := lru.GetOrLoad(ctx, "a", loader)
+		first <- err
+	}()
+	second := make(chan string, 1)
+	go func() {
+		val, _ := lru.GetOrLoad(context.Background(), "a", loader)
+		second <- val
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	if err := <-first; !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected cancelled caller to return context.Canceled, got %v", err)
+	}
+
+	close(release)
+	if err := <-loadCtxErr; err != nil {
+		t.Errorf("Expected shared load not to be cancelled, got %v", err)
+	}
+	if val := <-second; val != "value" {
+		t.Errorf("Expected other caller to get 'value', got '%s'", val)
+	}
+}
+
+func TestSoftTTL(t *testing.T) {
+	var calls atomic.Int32
+	var fail atomic.Bool
+	lru := cache.NewWithOptions(cache.Options[string, string]{
+		Capacity: 10,
+		Refresher: func(ctx context.Context, key string) (string, error) {
+			n := calls.Add(1)
+			time.Sleep(10 * time.Millisecond)
+			if fail.Load() {
+				return "", errors.New("source down")
+			}
+			return fmt.Sprintf("%s_v%d", key, n+1), nil
+		},
+	})
+	defer lru.Close()
+
+	lru.PutWithSoftTTL("a", "a_v1", 20*time.Millisecond, 200*time.Millisecond)
+	if val, _ := lru.Get("a"); val != "a_v1" {
+		t.Errorf("Expected fresh value 'a_v1', got '%s'", val)
+	}
+	if calls.Load() != 0 {
+		t.Error("Expected no refresh before the soft TTL")
+	}
+
+	// stale: every Get returns the old value at once, one refresh runs
+	time.Sleep(30 * time.Millisecond)
+	for i := 0; i < 5; i++ {
+		if val, ok := lru.Get("a"); !ok || val != "a_v1" {
+			t.Errorf("Expected stale value 'a_v1', got '%s':%t", val, ok)
+		}
+	}
+	waitFor(t, func() bool { return lru.Stats().Refreshes == 1 })
+	if calls.Load() != 1 {
+		t.Errorf("Expected 1 refresh, got %d", calls.Load())
+	}
+	if val, _ := lru.Peek("a"); val != "a_v2" {
+		t.Errorf("Expected refreshed value 'a_v2', got '%s'", val)
+	}
+
+	// a failed refresh keeps the stale value
+	fail.Store(true)
+	time.Sleep(30 * time.Millisecond)
+	lru.Get("a")
+	waitFor(t, func() bool { return lru.Stats().RefreshFailures == 1 })
+	if val, ok := lru.Get("a"); !ok || val != "a_v2" {
+		t.Errorf("Expected stale value 'a_v2' after a failed refresh, got '%s':%t", val, ok)
+	}
+	if stats := lru.Stats(); stats.StaleHits != 7 {
+		t.Errorf("Expected 7 stale hits, got %d", stats.StaleHits)
+	}
+
+	// past the hard TTL the entry is a miss
+	time.Sleep(200 * time.Millisecond)
+	if _, ok := lru.Get("a"); ok {
+		t.Error("Expected a miss after the hard TTL")
+	}
+}
+
+// waitFor polls cond until it holds or a second has passed
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	for deadline := time.Now().Add(time.Second); !cond(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("Timed out waiting for condition")
+		}
+	}
+}
+
+func TestWriteThrough(t *testing.T) {
+	store := cache.NewFakeStore[string, string]()
+	lru := cache.NewWithOptions(cache.Options[string, string]{Capacity: 1, Store: store})
+
+	lru.Put("a", "1")
+	lru.Put("b", "2") // evicts a from the cache only
+	if val, ok := store.Value("a"); !ok || val != "1" {
+		t.Errorf("Expected 'a' to be saved, got '%s':%t", val, ok)
+	}
+
+	if val, err := lru.Load(context.Background(), "a"); err != nil || val != "1" {
+		t.Errorf("Expected Load to read 'a' from the store, got '%s' (err %v)", val, err)
+	}
+	if _, err := lru.Load(context.Background(), "missing"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	lru.Delete("a")
+	if _, ok := store.Value("a"); ok {
+		t.Error("Expected 'a' to be deleted from the store")
+	}
+
+	errDown := errors.New("store down")
+	store.SetError(errDown)
+	if err := lru.Put("a", "3"); !errors.Is(err, errDown) {
+		t.Errorf("Expected store error, got %v", err)
+	}
+	if lru.Contains("a") {
+		t.Error("Expected failed write to leave the cache unchanged")
+	}
+
+	if _, saves, _ := store.Calls(); saves != 3 {
+		t.Errorf("Expected 3 saves, got %d", saves)
+	}
+}
+
+func TestUpdateCollectionWithStore(t *testing.T) {
+	addField := func(field string) func(cache.Value, bool) (cache.Value, error) {
+		return func(value cache.Value, exists bool) (cache.Value, error) {
+			value.(*cache.HashValue).Set(field, "1")
+			return value, nil
+		}
+	}
+
+	// a failed write-through save leaves the stored hash unchanged
+	store := cache.NewFakeStore[string, cache.Value]()
+	lru := cache.NewWithOptions(cache.Options[string, cache.Value]{Capacity: 10, Store: store})
+	lru.Put("h", cache.NewHashValue())
+	lru.Update("h", addField("a"))
+
+	errDown := errors.New("down")
+	store.SetError(errDown)
+	if _, err := lru.Update("h", addField("b")); !errors.Is(err, errDown) {
+		t.Errorf("Expected store error, got %v", err)
+	}
+	hash, _ := lru.Peek("h")
+	if _, found := hash.(*cache.HashValue).Get("b"); found || hash.(*cache.HashValue).Len() != 1 {
+		t.Error("Expected the failed update to leave the hash as it was")
+	}
+
+	// write-behind flushes do not race with updates of the same hash
+	fileStore, err := cache.NewFileStore[string, cache.Value](t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	lru = cache.NewWithOptions(cache.Options[string, cache.Value]{
+		Capacity:      10,
+		Store:         fileStore,
+		WriteMode:     cache.WriteBehind,
+		FlushInterval: time.Millisecond,
+	})
+	defer lru.Close()
+
+	lru.Put("h", cache.NewHashValue())
+	for i := 0; i < 200; i++ {
+		lru.Update("h", addField(fmt.Sprintf("f%d", i)))
+	}
+	lru.Flush()
+	if hash, _ := lru.Peek("h"); hash.(*cache.HashValue).Len() != 200 {
+		t.Errorf("Expected 200 fields, got %d", hash.(*cache.HashValue).Len())
+	}
+}
+
+func TestWriteBehind(t *testing.T) {
+	store := cache.NewFakeStore[string, string]()
+	lru := cache.NewWithOptions(cache.Options[string, string]{
+		Capacity:      10,
+		Store:         store,
+		WriteMode:     cache.WriteBehind,
+		FlushInterval: time.Hour,
+	})
+	defer lru.Close()
+
+	lru.Put("a", "1")
+	lru.Put("a", "2")
+	lru.Put("b", "1")
+	lru.Delete("b")
+
+	if store.Len() != 0 {
+		t.Error("Expected writes to be queued")
+	}
+
+	if err := lru.Flush(); err != nil {
+		t.Fatalf("Flush failed: %v", err)
+	}
+	if val, _ := store.Value("a"); val != "2" || store.Len() != 1 {
+		t.Errorf("Expected only 'a':'2' in the store, got %d keys", store.Len())
+	}
+	// writes of the same key are coalesced
+	if _, saves, deletes := store.Calls(); saves != 1 || deletes != 1 {
+		t.Errorf("Expected 1 save and 1 delete, got %d and %d", saves, deletes)
+	}
+}
+
+func TestWriteBehindEviction(t *testing.T) {
+	store := cache.NewFakeStore[string, string]()
+	lru := cache.NewWithOptions(cache.Options[string, string]{
+		Capacity:      1,
+		Store:         store,
+		WriteMode:     cache.WriteBehind,
+		FlushInterval: time.Hour,
+	})
+	defer lru.Close()
+
+	lru.Put("a", "1")
+	lru.Put("b", "2") // evicts dirty a
+
+	// the evicted value is readable before it reaches the store
+	if val, err := lru.Load(context.Background(), "a"); err != nil || val != "1" {
+		t.Errorf("Expected Load of evicted 'a' to return '1', got '%s' (err %v)", val, err)
+	}
+
+	for i := 0; i < 100 && store.Len() < 2; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if val, ok := store.Value("a"); !ok || val != "1" {
+		t.Errorf("Expected eviction to flush 'a', got '%s':%t", val, ok)
+	}
+}
+
+func TestWriteBehindRetry(t *testing.T) {
+	store := cache.NewFakeStore[string, string]()
+	var failed []string
+	lru := cache.NewWithOptions(cache.Options[string, string]{
+		Capacity:        10,
+		Store:           store,
+		WriteMode:       cache.WriteBehind,
+		FlushInterval:   time.Hour,
+		MaxWriteRetries: 1,
+		OnStoreError: func(key string, err error) {
+			failed = append(failed, key)
+		},
+	})
+	defer lru.Close()
+
+	store.SetError(errors.New("store down"))
+	lru.Put("a", "1")
+	lru.Put("b", "1")
+
+	if err := lru.Flush(); err == nil {
+		t.Error("Expected Flush to return the store errors")
+	}
+	store.SetError(nil)
+	lru.Put("b", "2")
+	store.SetError(errors.New("store down"))
+
+	lru.Flush() // retry of a fails, b is new
+	if fmt.Sprint(failed) != "[a]" {
+		t.Errorf("Expected only 'a' given up, got %v", failed)
+	}
+
+	store.SetError(nil)
+	lru.Flush()
+	if val, _ := store.Value("b"); val != "2" || store.Len() != 1 {
+		t.Errorf("Expected 'b':'2' to be retried, got '%s' and %d keys", val, store.Len())
+	}
+}
+
+func TestWriteBehindClose(t *testing.T) {
+	store := cache.NewFakeStore[string, string]()
+	lru := cache.NewWithOptions(cache.Options[string, string]{
+		Capacity:       10,
+		Store:          store,
+		WriteMode:      cache.WriteBehind,
+		FlushInterval:  time.Hour,
+		WriteQueueSize: 2,
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			lru.Put(fmt.Sprintf("key%d", i), "value") // blocks while the queue is full
+		}(i)
+	}
+	wg.Wait()
+	lru.Close()
+
+	if store.Len() != 20 {
+		t.Errorf("Expected all 20 writes flushed, got %d", store.Len())
+	}
+
+	lru.Put("late", "value")
+	if _, ok := store.Value("late"); !ok {
+		t.Error("Expected writes after Close to go to the store directly")
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+
+	store, err := cache.NewFileStore[string, int](dir)
+	if err != nil {
+		t.Fatalf("NewFileStore failed: %v", err)
+	}
+	lru := cache.NewWithOptions(cache.Options[string, int]{Capacity: 10, Store: store})
+	lru.Put("answer", 42)
+	lru.Put("gone", 1)
+	lru.Delete("gone")
+
+	// a new cache over the same directory sees the saved entries
+	reopened, _ := cache.NewFileStore[string, int](dir)
+	lru = cache.NewWithOptions(cache.Options[string, int]{Capacity: 10, Store: reopened})
+
+	if val, err := lru.Load(context.Background(), "answer"); err != nil || val != 42 {
+		t.Errorf("Expected 'answer':42, got %d (err %v)", val, err)
+	}
+	if _, err := lru.Load(context.Background(), "gone"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for deleted key, got %v", err)
+	}
+}
+
+func TestPeek(t *testing.T) {
+	lru := cache.NewLRUCache(2)
+
+	lru.Put("a", "1")
+	lru.Put("b", "2")
+	if val, ok := lru.Peek("a"); !ok || val != "1" {
+		t.Errorf("Expected 'a':'1', got '%s':%t", val, ok)
+	}
+	lru.Put("c", "3") // a was not promoted by Peek
+
+	if _, ok := lru.Peek("a"); ok {
+		t.Error("Expected 'a' to be evicted")
+	}
+	if stats := lru.Stats(); stats.Hits != 0 || stats.Misses != 0 {
+		t.Errorf("Expected Peek not to count hits or misses, got %+v", stats)
+	}
+}
+
+func TestIteration(t *testing.T) {
+	lru := cache.NewLRUCache(5)
+
+	if _, _, ok := lru.Oldest(); ok {
+		t.Error("Expected Oldest of empty cache to fail")
+	}
+
+	lru.Put("a", "1")
+	lru.Put("b", "2")
+	lru.Put("c", "3")
+	lru.PutWithTTL("expired", "x", time.Nanosecond)
+	lru.Get("a")
+	time.Sleep(time.Millisecond)
+
+	if keys := lru.Keys(); fmt.Sprint(keys) != "[a c b]" {
+		t.Errorf("Expected keys [a c b], got %v", keys)
+	}
+
+	var backward []string
+	for key, value := range lru.Backward() {
+		backward = append(backward, key+"="+value)
+	}
+	if fmt.Sprint(backward) != "[b=2 c=3 a=1]" {
+		t.Errorf("Expected [b=2 c=3 a=1], got %v", backward)
+	}
+
+	if key, _, _ := lru.Oldest(); key != "b" {
+		t.Errorf("Expected oldest 'b', got '%s'", key)
+	}
+	if key, value, _ := lru.Newest(); key != "a" || value != "1" {
+		t.Errorf("Expected newest 'a':'1', got '%s':'%s'", key, value)
+	}
+
+	// the loop body may modify the cache, iteration goes on over the snapshot
+	var seen []string
+	for key := range lru.All() {
+		seen = append(seen, key)
+		lru.Delete(key)
+		lru.Put("new_"+key, "x")
+	}
+	if fmt.Sprint(seen) != "[a c b]" {
+		t.Errorf("Expected to see [a c b], got %v", seen)
+	}
+
+	count := 0
+	lru.Range(func(key, value string) bool {
+		count++
+		return count < 2
+	})
+	if count != 2 {
+		t.Errorf("Expected Range to stop after 2 entries, got %d", count)
+	}
+}
+
+func TestResize(t *testing.T) {
+	lru := cache.NewLRUCache(5000)
+
+	evicted := 0
+	lru.OnRemoval(func(key, value string, reason cache.RemovalReason) {
+		if reason == cache.ReasonEvicted {
+			evicted++
+		}
+	})
+	for i := 0; i < 5000; i++ {
+		lru.Put(fmt.Sprintf("key_%d", i), "value")
+	}
+
+	// shrinking takes several batches
+	if err := lru.Resize(100); err != nil {
+		t.Fatalf("Resize failed: %v", err)
+	}
+	if lru.Size() != 100 || lru.Capacity() != 100 {
+		t.Errorf("Expected 100/100 items, got %d/%d", lru.Size(), lru.Capacity())
+	}
+	if evicted != 4900 || lru.Stats().Evictions != 4900 {
+		t.Errorf("Expected 4900 evictions, listener saw %d and stats %d", evicted, lru.Stats().Evictions)
+	}
+	if key, _, _ := lru.Oldest(); key != "key_4900" {
+		t.Errorf("Expected the newest items to stay, oldest is '%s'", key)
+	}
+
+	lru.Resize(200)
+	for i := 0; i < 100; i++ {
+		lru.Put(fmt.Sprintf("new_%d", i), "value")
+	}
+	if lru.Size() != 200 {
+		t.Errorf("Expected 200 items after growing, got %d", lru.Size())
+	}
+
+	if err := lru.Resize(0); !errors.Is(err, cache.ErrInvalidCapacity) {
+		t.Errorf("Expected ErrInvalidCapacity, got %v", err)
+	}
+}
+
+func TestResizeFromListener(t *testing.T) {
+	lru := cache.NewLRUCache(10)
+	for i := 0; i < 10; i++ {
+		lru.Put(fmt.Sprintf("key_%d", i), "value")
+	}
+
+	// the first eviction shrinks the cache again from the listener
+	resized := false
+	lru.OnRemoval(func(key, value string, reason cache.RemovalReason) {
+		if !resized {
+			resized = true
+			lru.Resize(2)
+		}
+	})
+
+	done := make(chan error, 1)
+	go func() { done <- lru.Resize(5) }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Resize failed: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Resize from a removal listener deadlocked")
+	}
+	if lru.Size() != 2 || lru.Capacity() != 2 {
+		t.Errorf("Expected 2/2 items, got %d/%d", lru.Size(), lru.Capacity())
+	}
+}
+
+func TestResizeBelowPinned(t *testing.T) {
+	lru := cache.NewWithOptions(cache.Options[string, string]{Capacity: 4000, MaxPinnedShare: 1})
+	for i := 0; i < 4000; i++ {
+		key := fmt.Sprintf("key_%d", i)
+		lru.Put(key, "value")
+		if i%2 == 0 && i < 3998 {
+			lru.Pin(key)
+		}
+	}
+
+	// 1999 pinned entries are more than the new capacity
+	done := make(chan error, 1)
+	go func() { done <- lru.Resize(100) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, cache.ErrPinnedFull) {
+			t.Errorf("Expected ErrPinnedFull, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Resize did not return")
+	}
+	if lru.Size() != 1999 || lru.Capacity() != 1999 {
+		t.Errorf("Expected the 1999 pinned items to stay as the capacity, got %d/%d", lru.Size(), lru.Capacity())
+	}
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	lru := cache.New[string, int](10)
+
+	lru.Put("a", 1)
+	_, v1, _ := lru.GetWithVersion("a")
+	lru.Put("a", 2)
+	_, v2, _ := lru.GetWithVersion("a")
+	if v2 <= v1 {
+		t.Errorf("Expected version to increase on Put, got %d then %d", v1, v2)
+	}
+
+	if _, err := lru.CompareAndSwap("a", v1, 3); !errors.Is(err, cache.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict, got %v", err)
+	}
+	if _, err := lru.CompareAndSwap("missing", v2, 3); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	v3, err := lru.CompareAndSwap("a", v2, 3)
+	if err != nil || v3 <= v2 {
+		t.Errorf("Expected CAS to succeed with a new version, got %d (err %v)", v3, err)
+	}
+
+	// a deleted and re-added key does not reuse old versions
+	lru.Delete("a")
+	lru.Put("a", 1)
+	if _, err := lru.CompareAndSwap("a", v3, 4); !errors.Is(err, cache.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict after re-adding the key, got %v", err)
+	}
+
+	// optimistic increments from many goroutines lose no update
+	lru.Put("counter", 0)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				for {
+					n, version, _ := lru.GetWithVersion("counter")
+					if _, err := lru.CompareAndSwap("counter", version, n+1); err == nil {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if n, _ := lru.Get("counter"); n != 1000 {
+		t.Errorf("Expected counter 1000, got %d", n)
+	}
+}
+
+// blockingStore is a FakeStore whose Save, once block is set, signals saving
+// and waits for release
+type blockingStore struct {
+	*cache.FakeStore[string, string]
+	block   atomic.Bool
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) Save(ctx context.Context, key, value string) error {
+	if s.block.Load() {
+		s.saving <- struct{}{}
+		<-s.release
+	}
+	return s.FakeStore.Save(ctx, key, value)
+}
+
+func TestCompareAndSwapWriteThroughConflict(t *testing.T) {
+	store := &blockingStore{
+		FakeStore: cache.NewFakeStore[string, string](),
+		saving:    make(chan struct{}),
+		release:   make(chan struct{}),
+	}
+	lru := cache.NewWithOptions(cache.Options[string, string]{
+		Capacity: 10,
+		Store:    store,
+		Refresher: func(ctx context.Context, key string) (string, error) {
+			return "refreshed", nil
+		},
+	})
+	defer lru.Close()
+
+	lru.PutWithSoftTTL("a", "1", 50*time.Millisecond, 0)
+	_, version, _ := lru.GetWithVersion("a")
+
+	store.block.Store(true)
+	result := make(chan error, 1)
+	go func() {
+		_, err := lru.CompareAndSwap("a", version, "2")
+		result <- err
+	}()
+	<-store.saving
+
+	// a refresh writes the entry while the CAS is writing the store
+	time.Sleep(60 * time.Millisecond)
+	lru.Get("a")
+	waitFor(t, func() bool { return lru.Stats().Refreshes == 1 })
+	close(store.release)
+
+	if err := <-result; !errors.Is(err, cache.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict, got %v", err)
+	}
+	if val, _ := lru.Peek("a"); val != "refreshed" {
+		t.Errorf("Expected the refreshed value to be kept, got %q", val)
+	}
+}
+
+func TestCounters(t *testing.T) {
+	lru := cache.NewLRUCache(10)
+
+	if n, err := cache.IncrBy(lru, "hits", 5); err != nil || n != 5 {
+		t.Errorf("Expected missing key to start at 0, got %d (err %v)", n, err)
+	}
+	if n, _ := cache.IncrBy(lru, "hits", -7); n != -2 {
+		t.Errorf("Expected -2, got %d", n)
+	}
+
+	lru.PutWithTTL("name", "gcache", time.Hour)
+	if _, err := cache.IncrBy(lru, "name", 1); !errors.Is(err, cache.ErrNotInteger) {
+		t.Errorf("Expected ErrNotInteger, got %v", err)
+	}
+	if val, _ := lru.Get("name"); val != "gcache" {
+		t.Errorf("Expected failed increment to keep the value, got '%s'", val)
+	}
+
+	lru.Put("max", "9223372036854775807")
+	if _, err := cache.IncrBy(lru, "max", 1); !errors.Is(err, cache.ErrOverflow) {
+		t.Errorf("Expected ErrOverflow, got %v", err)
+	}
+
+	if f, err := cache.IncrByFloat(lru, "hits", 0.5); err != nil || f != -1.5 {
+		t.Errorf("Expected -1.5, got %f (err %v)", f, err)
+	}
+	if val, _ := lru.Get("hits"); val != "-1.5" {
+		t.Errorf("Expected stored '-1.5', got '%s'", val)
+	}
+
+	// increments keep the expiration
+	lru.PutWithTTL("views", "1", time.Hour)
+	cache.IncrBy(lru, "views", 1)
+	if ttl, _ := lru.TTL("views"); ttl <= 0 {
+		t.Errorf("Expected TTL to be kept, got %v", ttl)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				cache.IncrBy(lru, "concurrent", 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if val, _ := lru.Get("concurrent"); val != "1000" {
+		t.Errorf("Expected 1000, got '%s'", val)
+	}
+}
+
+func TestViewMany(t *testing.T) {
+	lru := cache.New[string, int](10)
+	lru.Put("a", 1)
+	lru.Put("b", 2)
+
+	var got []string
+	lru.ViewMany([]string{"a", "missing", "b"}, func(i int, value int, exists bool) {
+		got = append(got, fmt.Sprintf("%d:%d:%t", i, value, exists))
+	})
+	if want := []string{"0:1:true", "1:0:false", "2:2:true"}; !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if stats := lru.Stats(); stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("Expected 2 hits and 1 miss, got %d and %d", stats.Hits, stats.Misses)
+	}
+}
+
+func TestUpdateInPlace(t *testing.T) {
+	lru := cache.New[string, map[string]int](10)
+
+	var removed []string
+	lru.OnRemoval(func(key string, value map[string]int, reason cache.RemovalReason) {
+		removed = append(removed, key+":"+reason.String())
+	})
+
+	add := func(value map[string]int, exists bool) (map[string]int, error) {
+		if !exists {
+			value = map[string]int{}
+		}
+		value["n"]++
+		return value, nil
+	}
+	lru.Update("counts", add)
+	lru.Update("counts", add)
+
+	n := 0
+	if !lru.View("counts", func(value map[string]int) { n = value["n"] }) || n != 2 {
+		t.Errorf("Expected n=2, got %d", n)
+	}
+	if lru.View("missing", func(map[string]int) { t.Error("Expected fn not to be called") }) {
+		t.Error("Expected View of missing key to report false")
+	}
+
+	lru.Update("counts", func(value map[string]int, exists bool) (map[string]int, error) {
+		return value, cache.RemoveEntry
+	})
+	if lru.Contains("counts") {
+		t.Error("Expected RemoveEntry to delete the entry")
+	}
+	if fmt.Sprint(removed) != "[counts:replaced counts:deleted]" {
+		t.Errorf("Expected replaced then deleted, got %v", removed)
+	}
+}
+
+func TestSortedSetValue(t *testing.T) {
+	zset := cache.NewSortedSetValue()
+	scores := make(map[string]float64)
+
+	for i := 0; i < 2000; i++ {
+		member := fmt.Sprintf("m%d", rand.Intn(300))
+		if rand.Intn(4) == 0 {
+			zset.Remove(member)
+			delete(scores, member)
+		} else {
+			score := float64(rand.Intn(50)) // many ties, ordered by member
+			zset.Add(member, score)
+			scores[member] = score
+		}
+	}
+
+	members := slices.Collect(maps.Keys(scores))
+	slices.SortFunc(members, func(a, b string) int {
+		return cmp.Or(cmp.Compare(scores[a], scores[b]), strings.Compare(a, b))
+	})
+	if zset.Len() != len(members) {
+		t.Fatalf("Expected %d members, got %d", len(members), zset.Len())
+	}
+	for i, member := range members {
+		if rank, ok := zset.Rank(member); !ok || rank != i {
+			t.Fatalf("Expected %s at rank %d, got %d (%v)", member, i, rank, ok)
+		}
+	}
+
+	// memory accounting follows the member count
+	size := zset.Size()
+	zset.Add("newcomer", 1)
+	if zset.Size() <= size {
+		t.Errorf("Expected size to grow with a member, %d -> %d", size, zset.Size())
+	}
+	zset.Remove("newcomer")
+	if zset.Size() != size {
+		t.Errorf("Expected size %d after removing the member, got %d", size, zset.Size())
+	}
+}
+
+func TestHashValue(t *testing.T) {
+	hash := cache.NewHashValue()
+	empty := hash.Size()
+
+	if !hash.Set("name", "ada") || hash.Set("name", "grace") {
+		t.Error("Expected Set to report only the new field")
+	}
+	hash.Set("lang", "en")
+	size := hash.Size()
+
+	// the size is tracked on each change rather than recomputed
+	hash.Set("name", "ada")
+	if hash.Size() != size-2 {
+		t.Errorf("Expected size %d after a shorter value, got %d", size-2, hash.Size())
+	}
+	if !hash.Delete("name") || !hash.Delete("lang") || hash.Delete("lang") {
+		t.Error("Expected Delete to report only existing fields")
+	}
+	if hash.Size() != empty || hash.Len() != 0 {
+		t.Errorf("Expected an empty hash of size %d, got %d fields, size %d", empty, hash.Len(), hash.Size())
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	set := cache.NewSetValue()
+	empty := set.Size()
+
+	if !set.Add("a") || set.Add("a") || !set.Add("bcd") {
+		t.Error("Expected Add to report only new members")
+	}
+	if !set.Contains("bcd") || set.Len() != 2 {
+		t.Errorf("Expected 2 members including 'bcd', got %v", set.Members())
+	}
+
+	// the size is tracked on each change rather than recomputed
+	size := set.Size()
+	set.Remove("bcd")
+	if set.Size() >= size || set.Remove("bcd") {
+		t.Errorf("Expected size to shrink once, %d -> %d", size, set.Size())
+	}
+	set.Remove("a")
+	if set.Size() != empty {
+		t.Errorf("Expected size %d for an empty set, got %d", empty, set.Size())
+	}
+}
+
+func TestTags(t *testing.T) {
+	lru := cache.New[string, string](3)
+
+	var invalidated []string
+	lru.OnRemoval(func(key, value string, reason cache.RemovalReason) {
+		if reason == cache.ReasonInvalidated {
+			invalidated = append(invalidated, key)
+		}
+	})
+
+	lru.PutTagged("page:1", "<html>", 0, "product:7", "product:9")
+	lru.PutTagged("page:2", "<html>", 0, "product:7", "product:7")
+	lru.PutTagged("fragment", "<div>", 0, "product:9")
+
+	if tags := lru.Tags("page:2"); fmt.Sprint(tags) != "[product:7]" {
+		t.Errorf("Expected deduplicated tags, got %v", tags)
+	}
+
+	// Update keeps the tags, a plain Put drops them
+	lru.Update("page:1", func(value string, exists bool) (string, error) { return value + "!", nil })
+	lru.Put("page:2", "<html> v2")
+	if tags := lru.Tags("page:1"); len(tags) != 2 {
+		t.Errorf("Expected Update to keep the tags, got %v", tags)
+	}
+
+	if n := lru.InvalidateTag("product:7"); n != 1 {
+		t.Errorf("Expected 1 invalidated entry, got %d", n)
+	}
+	if fmt.Sprint(invalidated) != "[page:1]" {
+		t.Errorf("Expected listener to see page:1 invalidated, got %v", invalidated)
+	}
+	if !lru.Contains("page:2") || !lru.Contains("fragment") {
+		t.Error("Expected untagged and other-tag entries to stay")
+	}
+	if n := lru.InvalidateTag("product:7"); n != 0 {
+		t.Errorf("Expected nothing left to invalidate, got %d", n)
+	}
+
+	// the index follows delete, expiry and eviction
+	lru.Delete("fragment")
+	lru.PutTagged("short", "x", time.Millisecond, "temp")
+	time.Sleep(5 * time.Millisecond)
+	if n := lru.InvalidateTag("temp"); n != 0 {
+		t.Errorf("Expected expired entry not to count, got %d", n)
+	}
+
+	for i := 0; i < 100; i++ {
+		lru.PutTagged(fmt.Sprintf("key%d", i), "v", 0, fmt.Sprintf("tag%d", i), "shared")
+	}
+	if lru.TagCount() != 4 {
+		t.Errorf("Expected tags of the 3 remaining entries plus 'shared', got %d", lru.TagCount())
+	}
+	if n := lru.InvalidateTag("shared"); n != 3 {
+		t.Errorf("Expected 3 invalidated entries, got %d", n)
+	}
+	if lru.TagCount() != 0 {
+		t.Errorf("Expected empty tag index, got %d tags", lru.TagCount())
+	}
+}
+
+func TestPinnedAndPriority(t *testing.T) {
+	lru := cache.NewWithOptions(cache.Options[string, int]{Capacity: 4, MaxPinnedShare: 0.5})
+
+	lru.PutPinned("flag", 0, 0)
+	lru.Put("high", 1)
+	lru.Put("low", 2)
+	lru.Put("normal", 3)
+	if err := lru.SetPriority("high", cache.PriorityHigh); err != nil {
+		t.Fatalf("SetPriority failed: %v", err)
+	}
+	lru.SetPriority("low", cache.PriorityLow)
+
+	// the low class is drained first, then the normal one, by recency
+	for i, evicted := range []string{"low", "normal", "k0", "k1", "k2"} {
+		lru.Put(fmt.Sprintf("k%d", i), i)
+		if lru.Contains(evicted) {
+			t.Errorf("Expected '%s' to be evicted by k%d", evicted, i)
+		}
+	}
+	for _, key := range []string{"flag", "high"} {
+		if !lru.Contains(key) {
+			t.Errorf("Expected '%s' to survive evictions", key)
+		}
+	}
+
+	// overwriting keeps the pin and the priority
+	lru.Put("high", 10)
+	lru.Put("flag", 10)
+	lru.Put("k5", 5)
+	if !lru.Contains("flag") || !lru.Contains("high") {
+		t.Error("Expected overwritten entries to keep their class")
+	}
+
+	if err := lru.Pin("high"); err != nil {
+		t.Fatalf("Pin failed: %v", err)
+	}
+	if err := lru.Pin("k5"); !errors.Is(err, cache.ErrPinLimit) {
+		t.Errorf("Expected ErrPinLimit past half of the capacity, got %v", err)
+	}
+	if count, _ := lru.Pinned(); count != 2 || lru.PinnedShare() != 0.5 {
+		t.Errorf("Expected 2 pinned entries using 0.5, got %d using %v", count, lru.PinnedShare())
+	}
+
+	// with all evictable entries gone, pinned ones are still kept
+	lru.Delete("k4")
+	lru.Delete("k5")
+	for i := 0; i < 10; i++ {
+		lru.Put(fmt.Sprintf("n%d", i), i)
+	}
+	if !lru.Contains("flag") || !lru.Contains("high") || lru.Size() != 4 {
+		t.Errorf("Expected both pinned entries in a full cache, got %v", lru.Keys())
+	}
+
+	if !lru.Unpin("flag") || lru.Unpin("flag") {
+		t.Error("Expected Unpin to report only the first call")
+	}
+	lru.Delete("high")
+	if count, bytes := lru.Pinned(); count != 0 || bytes != 0 {
+		t.Errorf("Expected no pinned entries left, got %d (%d bytes)", count, bytes)
+	}
+
+	if err := lru.Pin("missing"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if err := lru.SetPriority("flag", 5); !errors.Is(err, cache.ErrInvalidPriority) {
+		t.Errorf("Expected ErrInvalidPriority, got %v", err)
+	}
+}
+
+func TestPutPinnedFull(t *testing.T) {
+	lru := cache.NewWithOptions(cache.Options[string, string]{
+		MaxBytes: 10000,
+		Weigher:  func(key, value string) int64 { return int64(len(value)) },
+	})
+
+	if err := lru.PutPinned("pinned", strings.Repeat("p", 5000), 0); err != nil {
+		t.Fatalf("PutPinned failed: %v", err)
+	}
+	lru.Put("small", strings.Repeat("s", 3000))
+
+	if err := lru.Put("big", strings.Repeat("b", 9000)); !errors.Is(err, cache.ErrPinnedFull) {
+		t.Errorf("Expected ErrPinnedFull, got %v", err)
+	}
+	if err := lru.Put("small", strings.Repeat("s", 6000)); !errors.Is(err, cache.ErrPinnedFull) {
+		t.Errorf("Expected ErrPinnedFull when overwriting, got %v", err)
+	}
+	if val, _ := lru.Peek("small"); len(val) != 3000 || lru.UsedBytes() != 8000 || lru.Size() != 2 {
+		t.Errorf("Expected the cache unchanged, got %d items using %d bytes, small has %d", lru.Size(), lru.UsedBytes(), len(val))
+	}
+
+	// evicting the unpinned entries makes room
+	if err := lru.Put("big", strings.Repeat("b", 5000)); err != nil || lru.UsedBytes() != 10000 {
+		t.Errorf("Expected big to fit, got %d bytes (err %v)", lru.UsedBytes(), err)
+	}
+}
+
+func TestInspect(t *testing.T) {
+	lru := cache.NewWithOptions(cache.Options[string, string]{Capacity: 3, Policy: cache.PolicyLRU})
+
+	before := time.Now()
+	lru.Put("a", "1")
+	lru.Put("b", "22")
+	lru.Put("c", "333")
+	time.Sleep(20 * time.Millisecond)
+
+	lru.Get("a")
+	lru.Put("a", "4444")
+	info, ok := lru.Inspect("a")
+	if !ok {
+		t.Fatal("Expected to inspect 'a'")
+	}
+	if info.CreatedAt.Before(before) || !info.AccessedAt.After(info.CreatedAt.Add(10*time.Millisecond)) {
+		t.Errorf("Expected creation before the last access, got %v and %v", info.CreatedAt, info.AccessedAt)
+	}
+	if info.AccessCount != 3 || info.Idle() > 10*time.Millisecond {
+		t.Errorf("Expected 3 recent accesses, got %d idle for %v", info.AccessCount, info.Idle())
+	}
+
+	// Inspect is not an access
+	lru.Inspect("b")
+	b, _ := lru.Inspect("b")
+	if b.AccessCount != 1 || b.Idle() < 20*time.Millisecond {
+		t.Errorf("Expected 1 access 20ms ago, got %d idle for %v", b.AccessCount, b.Idle())
+	}
+	if c, _ := lru.Inspect("c"); c.Size <= b.Size {
+		t.Errorf("Expected the longer value to weigh more, got %d and %d", c.Size, b.Size)
+	}
+	if _, ok := lru.Inspect("missing"); ok {
+		t.Error("Expected no info for a missing key")
+	}
+
+	if keys := lru.Coldest(2); !slices.Equal(keys, []string{"b", "c"}) {
+		t.Errorf("Expected coldest [b c], got %v", keys)
+	}
+	if keys := lru.Coldest(10); !slices.Equal(keys, []string{"b", "c", "a"}) {
+		t.Errorf("Expected all keys coldest first, got %v", keys)
+	}
+}
+
+func TestScan(t *testing.T) {
+	lru := cache.New[string, int](100000)
+	for i := 0; i < 1000; i++ {
+		lru.Put(fmt.Sprintf("stable%d", i), i)
+		lru.Put(fmt.Sprintf("volatile%d", i), i)
+	}
+
+	// churn the other keys during the scan, enough to compact the scan index
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			lru.Delete(fmt.Sprintf("volatile%d", i%1000))
+			lru.Put(fmt.Sprintf("new%d", i), i)
+			lru.Delete(fmt.Sprintf("new%d", i-1))
+			lru.Put(fmt.Sprintf("stable%d", i%1000), -1) // overwrites keep their place
+		}
+	}()
+
+	seen := make(map[string]int)
+	calls := 0
+	for cursor := uint64(0); ; {
+		var keys []string
+		keys, cursor = lru.Scan(cursor, 7)
+		for _, key := range keys {
+			seen[key]++
+		}
+		calls++
+		if cursor == 0 {
+			break
+		}
+	}
+	close(stop)
+	<-done
+
+	for i := 0; i < 1000; i++ {
+		if n := seen[fmt.Sprintf("stable%d", i)]; n != 1 {
+			t.Fatalf("Expected stable%d to be returned once, got %d", i, n)
+		}
+	}
+	if calls < 2000/7 {
+		t.Errorf("Expected at least %d calls of 7 entries, got %d", 2000/7, calls)
+	}
+
+	lru.Clear()
+	if keys, cursor := lru.Scan(0, 10); len(keys) != 0 || cursor != 0 {
+		t.Errorf("Expected empty scan after Clear, got %v %d", keys, cursor)
+	}
+}
+
 //Benchmark tests
 
 func BenchmarkPut(b *testing.B) {
@@ -222,6 +1851,30 @@ func BenchmarkPut(b *testing.B) {
 	}
 }
 
+func BenchmarkPutEvict(b *testing.B) {
+	cache := cache.NewLRUCache(1000)
+
+	// more distinct keys than capacity, so every Put inserts and evicts
+	keys := make([]string, 4096)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key_%d", i)
+	}
+
+	b.ReportAllocs()
+	for i := 0; b.Loop(); i++ {
+		cache.Put(keys[i%len(keys)], "value")
+	}
+}
+
+func BenchmarkGenericPutEvict(b *testing.B) {
+	cache := cache.New[int64, int64](1000)
+
+	b.ReportAllocs()
+	for i := int64(0); b.Loop(); i++ {
+		cache.Put(i%4096, i)
+	}
+}
+
 func BenchmarkGet(b *testing.B) {
 	cache := cache.NewLRUCache(1000)
 
@@ -252,3 +1905,85 @@ func BenchmarkConcurrent(b *testing.B) {
 		}
 	})
 }
+
+// hit ratio on a Zipfian workload interrupted by scans of one-off keys,
+// as a nightly batch job would produce, with and without admission
+func BenchmarkZipfWithScan(b *testing.B) {
+	for _, admission := range []bool{false, true} {
+		for _, policy := range []string{cache.PolicyLRU, cache.PolicyLFU} {
+			name := policy
+			if admission {
+				name += "+tinylfu"
+			}
+
+			b.Run(name, func(b *testing.B) {
+				lru := cache.NewWithOptions(cache.Options[uint64, int]{
+					Capacity:  1000,
+					Policy:    policy,
+					Admission: admission,
+				})
+				zipf := rand.NewZipf(rand.New(rand.NewSource(42)), 1.1, 1, 100000)
+
+				hits, requests := 0, 0
+				scanKey := uint64(1 << 32)
+				for i := 0; b.Loop(); i++ {
+					key := zipf.Uint64()
+					if i%10000 < 2000 {
+						// scan phase: 20% of the requests are unique keys
+						key = scanKey
+						scanKey++
+					}
+
+					requests++
+					if _, ok := lru.Get(key); ok {
+						hits++
+					} else {
+						lru.Put(key, i)
+					}
+				}
+
+				b.ReportMetric(100*float64(hits)/float64(requests), "hit%")
+			})
+		}
+	}
+}
+
+// single lock vs sharded cache under parallel load, with a read-heavy mix
+func BenchmarkParallel(b *testing.B) {
+	keys := make([]string, 1000)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key_%d", i)
+	}
+
+	run := func(b *testing.B, get func(string) (string, bool), put func(string, string) error) {
+		for _, key := range keys {
+			put(key, "value")
+		}
+
+		b.ResetTimer()
+		b.RunParallel(func(pb *testing.PB) {
+			i := 0
+			for pb.Next() {
+				key := keys[i%len(keys)]
+				if i%4 == 0 {
+					put(key, "value")
+				} else {
+					get(key)
+				}
+				i++
+			}
+		})
+	}
+
+	b.Run("single", func(b *testing.B) {
+		lru := cache.NewLRUCache(len(keys))
+		run(b, lru.Get, lru.Put)
+	})
+
+	for _, shards := range []int{4, 16, 64} {
+		b.Run(fmt.Sprintf("sharded-%d", shards), func(b *testing.B) {
+			sharded := cache.NewSharded[string, string](shards, len(keys))
+			run(b, sharded.Get, sharded.Put)
+		})
+	}
+}
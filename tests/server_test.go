@@ -0,0 +1,866 @@
+package tests
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ayushvyas-1/gcache/internal/cache"
+)
+
+// startTestServer runs a server on a free local port and returns a connected client
+func startTestServer(t *testing.T, config cache.ServerConfig) *cache.Client {
+	t.Helper()
+
+	_, addr := startServer(t, config)
+	return connect(t, addr)
+}
+
+// startServer runs a server on a free local port and returns it with its address
+func startServer(t *testing.T, config cache.ServerConfig) (*cache.Server, string) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find free port: %v", err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	config.Address = addr
+	server := cache.NewServerWithConfig(config)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		server.Start()
+	}()
+	t.Cleanup(func() {
+		server.Stop()
+		<-done
+	})
+	return server, addr
+}
+
+// connect returns a client of the server at addr, waiting for it to start
+func connect(t *testing.T, addr string) *cache.Client {
+	t.Helper()
+
+	for i := 0; i < 100; i++ {
+		client, err := cache.NewClient(addr)
+		if err == nil {
+			t.Cleanup(client.Close)
+			return client
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("Server did not start on %s", addr)
+	return nil
+}
+
+// expectResponse sends a raw command and checks the raw reply
+func expectResponse(t *testing.T, client *cache.Client, command, want string) {
+	t.Helper()
+
+	got, err := client.SendCommand(command)
+	if err != nil {
+		t.Fatalf("%s: %v", command, err)
+	}
+	if got != want {
+		t.Errorf("%s: expected %q, got %q", command, want, got)
+	}
+}
+
+func TestServerExpiration(t *testing.T) {
+	client := startTestServer(t, cache.ServerConfig{Capacity: 10})
+
+	expectResponse(t, client, "SET plain hello world", "+OK")
+	expectResponse(t, client, "TTL plain", ":-1")
+	expectResponse(t, client, "TTL missing", ":-2")
+	expectResponse(t, client, "EXPIRE missing 10", ":0")
+
+	expectResponse(t, client, "SET session abc EX 100", "+OK")
+	expectResponse(t, client, "TTL session", ":100")
+	expectResponse(t, client, "PERSIST session", ":1")
+	expectResponse(t, client, "TTL session", ":-1")
+	expectResponse(t, client, "PERSIST session", ":0")
+
+	expectResponse(t, client, "EXPIRE plain 100", ":1")
+	expectResponse(t, client, "GET plain", "+hello world")
+	expectResponse(t, client, "SET bad value EX 0", "-ERR invalid expire time in 'set' command")
+
+	// expire times that overflow are rejected rather than wrapped around
+	expectResponse(t, client, "EXPIRE plain 9300000000", "-ERR invalid expire time in 'expire' command")
+	expectResponse(t, client, "PEXPIRE plain 9300000000000000", "-ERR invalid expire time in 'pexpire' command")
+	expectResponse(t, client, "GET plain", "+hello world")
+	expectResponse(t, client, "SET big value EX 9300000000", "-ERR invalid expire time in 'set' command")
+	expectResponse(t, client, "SET big value PX 9300000000000000", "-ERR invalid expire time in 'set' command")
+
+	// an expire time in the past deletes the key, like Redis
+	expectResponse(t, client, "SET past value", "+OK")
+	expectResponse(t, client, "SET past value EXAT 1", "+OK")
+	expectResponse(t, client, "TTL past", ":-2")
+	expectResponse(t, client, "SET past value", "+OK")
+	expectResponse(t, client, "SETTAGGED past value PXAT 1000 TAGS old", "+OK")
+	expectResponse(t, client, "GET past", "-ERR key not found")
+
+	// lower case words at the end of a value are not options
+	expectResponse(t, client, "SET latin deus ex machina", "+OK")
+	expectResponse(t, client, "GET latin", "+deus ex machina")
+	expectResponse(t, client, "SET chore run ex 5", "+OK")
+	expectResponse(t, client, "GET chore", "+run ex 5")
+	expectResponse(t, client, "TTL chore", ":-1")
+
+	if err := client.SetWithTTL("token", "xyz", 0); err == nil || err.Error() != "invalid expire time in 'set' command" {
+		t.Errorf("Expected the invalid expire time error, got %v", err)
+	}
+	if err := client.SetWithTTL("token", "xyz", 50*time.Millisecond); err != nil {
+		t.Fatalf("SetWithTTL failed: %v", err)
+	}
+	if ttl, err := client.TTL("token"); err != nil || ttl <= 0 || ttl > 50*time.Millisecond {
+		t.Errorf("Expected TTL in (0, 50ms], got %v (err %v)", ttl, err)
+	}
+
+	time.Sleep(80 * time.Millisecond)
+
+	if _, err := client.Get("token"); err == nil {
+		t.Error("Expected 'token' to be expired")
+	}
+	if _, err := client.TTL("token"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for the TTL of an expired key, got %v", err)
+	}
+}
+
+func TestServerMaxMemory(t *testing.T) {
+	client := startTestServer(t, cache.ServerConfig{MaxMemory: 1024})
+
+	expectResponse(t, client, "SET small value", "+OK")
+
+	large := strings.Repeat("x", 2048)
+	expectResponse(t, client, "SET large "+large, "-ERR entry exceeds cache max memory")
+
+	stats, err := client.SendCommand("STATS")
+	if err != nil {
+		t.Fatalf("STATS failed: %v", err)
+	}
+	if !strings.Contains(stats, "maxmemory:1024") || strings.Contains(stats, "used_memory:0 ") {
+		t.Errorf("Expected STATS to report memory usage, got %q", stats)
+	}
+
+	// a collection write rejected as too large leaves the old value and size
+	for _, write := range []struct{ create, grow, read string }{
+		{"HSET h f v", "HSET h big " + large, "HGET h big"},
+		{"LPUSH l v", "LPUSH l " + large, "LLEN l"},
+		{"SADD s v", "SADD s " + large, "SCARD s"},
+		{"ZADD z 1 v", "ZADD z 2 " + large, "ZCARD z"},
+	} {
+		key := strings.Fields(write.create)[1]
+		client.SendCommand(write.create)
+		before, _ := client.SendCommand("MEMORY USAGE " + key)
+		readBefore, _ := client.SendCommand(write.read)
+
+		expectResponse(t, client, write.grow, "-ERR entry exceeds cache max memory")
+		expectResponse(t, client, write.read, readBefore)
+		expectResponse(t, client, "MEMORY USAGE "+key, before)
+	}
+}
+
+func TestServerPolicy(t *testing.T) {
+	client := startTestServer(t, cache.ServerConfig{Capacity: 10, Policy: cache.PolicyARC})
+
+	info, err := client.Info()
+	if err != nil {
+		t.Fatalf("INFO failed: %v", err)
+	}
+	if !strings.Contains(info, "eviction_policy:arc") {
+		t.Errorf("Expected INFO to report the arc policy, got %q", info)
+	}
+}
+
+func TestServerStats(t *testing.T) {
+	client := startTestServer(t, cache.ServerConfig{Capacity: 10})
+
+	client.Set("a", "1")
+	client.Get("a")
+	client.Get("a")
+	client.Get("a")
+	client.Get("missing")
+
+	stats, err := client.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Size != 1 || stats.Capacity != 10 || stats.Hits != 3 || stats.Misses != 1 || stats.Sets != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+	if stats.HitRatio != 0.75 {
+		t.Errorf("Expected hit ratio 0.75, got %f", stats.HitRatio)
+	}
+
+	if err := client.ResetStats(); err != nil {
+		t.Fatalf("ResetStats failed: %v", err)
+	}
+	if stats, _ := client.Stats(); stats.Hits != 0 || stats.Size != 1 {
+		t.Errorf("Expected counters reset and data kept, got %+v", stats)
+	}
+
+	reply, err := client.SendCommand("STATS")
+	if err != nil || !strings.Contains(reply, " stale_hits:0 refreshes:0 refresh_failures:0 ") {
+		t.Errorf("Expected the soft TTL counters in %q (err %v)", reply, err)
+	}
+}
+
+func TestClientStatsRefreshCounters(t *testing.T) {
+	// the server has no soft TTL command, so a fake server sends the counters
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen failed: %v", err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		buf := make([]byte, 64)
+		conn.Read(buf)
+		conn.Write([]byte("+size:1 capacity:10 hits:4 stale_hits:3 refreshes:2 refresh_failures:1\r\n"))
+	}()
+
+	client := connect(t, l.Addr().String())
+	stats, err := client.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Hits != 4 || stats.StaleHits != 3 || stats.Refreshes != 2 || stats.RefreshFailures != 1 {
+		t.Errorf("Expected stale_hits 3, refreshes 2 and refresh_failures 1, got %+v", stats)
+	}
+}
+
+func TestServerConfig(t *testing.T) {
+	client := startTestServer(t, cache.ServerConfig{Capacity: 10})
+
+	for i := 0; i < 10; i++ {
+		client.Set(fmt.Sprintf("key%d", i), "value")
+	}
+
+	if err := client.ConfigSet("capacity", "4"); err != nil {
+		t.Fatalf("ConfigSet failed: %v", err)
+	}
+	if capacity, err := client.ConfigGet("capacity"); err != nil || capacity != "4" {
+		t.Errorf("Expected capacity 4, got %q (err %v)", capacity, err)
+	}
+	if size, _ := client.Size(); size != 4 {
+		t.Errorf("Expected 4 items after shrinking, got %d", size)
+	}
+
+	expectResponse(t, client, "CONFIG SET capacity abc", "-ERR value is not an integer")
+	expectResponse(t, client, "CONFIG SET capacity 0", "-ERR invalid capacity")
+	expectResponse(t, client, "CONFIG SET maxmemory 10", "-ERR unsupported CONFIG parameter 'maxmemory'")
+	expectResponse(t, client, "CONFIG RESET capacity", "-ERR unknown CONFIG subcommand 'RESET'")
+	expectResponse(t, client, "CONFIG GET eviction_policy", "+lru")
+
+	// pinned keys are not evicted, the capacity stops at their number
+	client.Pin("key8")
+	client.Pin("key9")
+	expectResponse(t, client, "CONFIG SET capacity 1", "-ERR pinned entries leave no room in the cache")
+	expectResponse(t, client, "CONFIG GET capacity", ":2")
+	expectResponse(t, client, "GET key9", "+value")
+}
+
+func TestServerCAS(t *testing.T) {
+	client := startTestServer(t, cache.ServerConfig{Capacity: 10})
+
+	client.Set("profile", "name=ada")
+	value, version, err := client.Gets("profile")
+	if err != nil || value != "name=ada" {
+		t.Fatalf("Expected 'name=ada', got %q (err %v)", value, err)
+	}
+
+	newVersion, err := client.CAS("profile", "name=ada lovelace", version)
+	if err != nil || newVersion <= version {
+		t.Fatalf("Expected CAS to succeed with a new version, got %d (err %v)", newVersion, err)
+	}
+	if value, _, _ := client.Gets("profile"); value != "name=ada lovelace" {
+		t.Errorf("Expected value with spaces to be kept, got %q", value)
+	}
+
+	if _, err := client.CAS("profile", "stale", version); !errors.Is(err, cache.ErrVersionConflict) {
+		t.Errorf("Expected ErrVersionConflict, got %v", err)
+	}
+	if _, err := client.CAS("missing", "x", version); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	if _, _, err := client.Gets("missing"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+
+	expectResponse(t, client, "CAS profile abc value", "-ERR invalid version")
+	expectResponse(t, client, "CAS profile 1", "-ERR wrong number of arguments for 'CAS' command")
+}
+
+func TestServerCounters(t *testing.T) {
+	client := startTestServer(t, cache.ServerConfig{Capacity: 10})
+
+	expectResponse(t, client, "INCR visits", ":1")
+	expectResponse(t, client, "INCRBY visits 10", ":11")
+	expectResponse(t, client, "DECR visits", ":10")
+	expectResponse(t, client, "DECRBY visits 20", ":-10")
+	expectResponse(t, client, "INCRBY visits ten", "-ERR value is not an integer")
+
+	client.Set("name", "gcache")
+	expectResponse(t, client, "INCR name", "-ERR value is not an integer")
+
+	if n, err := client.IncrBy("visits", 15); err != nil || n != 5 {
+		t.Errorf("Expected 5, got %d (err %v)", n, err)
+	}
+	if f, err := client.IncrByFloat("price", 10.5); err != nil || f != 10.5 {
+		t.Errorf("Expected 10.5, got %f (err %v)", f, err)
+	}
+	if f, _ := client.IncrByFloat("price", -0.25); f != 10.25 {
+		t.Errorf("Expected 10.25, got %f", f)
+	}
+	if _, err := client.Incr("price"); err == nil {
+		t.Error("Expected INCR of a float to fail")
+	}
+}
+
+func TestServerHash(t *testing.T) {
+	client := startTestServer(t, cache.ServerConfig{Capacity: 2})
+
+	if n, err := client.HSet("user:1", map[string]string{"name": "ada", "lang": "en"}); err != nil || n != 2 {
+		t.Fatalf("Expected 2 new fields, got %d (err %v)", n, err)
+	}
+	expectResponse(t, client, "HSET user:1 name grace visits 1", ":1")
+	expectResponse(t, client, "HGET user:1 name", "+grace")
+	expectResponse(t, client, "HGET user:1 missing", "-ERR field not found")
+	expectResponse(t, client, "HMGET user:1 lang missing", "*2\n+en\n$-1")
+	expectResponse(t, client, "HINCRBY user:1 visits 5", ":6")
+	expectResponse(t, client, "HINCRBY user:1 name 1", "-ERR hash value is not an integer")
+	expectResponse(t, client, "HLEN user:1", ":3")
+	expectResponse(t, client, "TYPE user:1", "+hash")
+
+	hash, err := client.HGetAll("user:1")
+	expected := map[string]string{"name": "grace", "lang": "en", "visits": "6"}
+	if err != nil || fmt.Sprint(hash) != fmt.Sprint(expected) {
+		t.Errorf("Expected %v, got %v (err %v)", expected, hash, err)
+	}
+	if fields, _ := client.HMGet("user:1", "name", "missing"); fmt.Sprint(fields) != "map[name:grace]" {
+		t.Errorf("Expected only the existing field, got %v", fields)
+	}
+
+	// the hash is one entry, promoted by field access
+	client.Set("a", "1")
+	client.HGet("user:1", "name")
+	client.Set("b", "2")
+	if exists, _ := client.HExists("user:1", "name"); !exists {
+		t.Error("Expected hash to survive eviction after field access")
+	}
+	if _, err := client.Get("a"); err == nil {
+		t.Error("Expected 'a' to be evicted")
+	}
+
+	if _, err := client.Get("user:1"); !errors.Is(err, cache.ErrWrongType) {
+		t.Errorf("Expected ErrWrongType for GET on a hash, got %v", err)
+	}
+	if _, err := client.HGet("b", "field"); !errors.Is(err, cache.ErrWrongType) {
+		t.Errorf("Expected ErrWrongType for HGET on a string, got %v", err)
+	}
+	expectResponse(t, client, "INCR user:1", "-WRONGTYPE Operation against a key holding the wrong kind of value")
+
+	// deleting the last field deletes the key
+	client.HDel("user:1", "name", "lang")
+	if n, _ := client.HDel("user:1", "visits", "missing"); n != 1 {
+		t.Errorf("Expected 1 deleted field, got %d", n)
+	}
+	expectResponse(t, client, "TYPE user:1", "+none")
+	expectResponse(t, client, "HGETALL user:1", "*0")
+}
+
+func TestServerList(t *testing.T) {
+	client := startTestServer(t, cache.ServerConfig{Capacity: 10})
+
+	expectResponse(t, client, "RPUSH queue b c", ":2")
+	expectResponse(t, client, "LPUSH queue a", ":3")
+	expectResponse(t, client, "LRANGE queue 0 -1", "*3\n+a\n+b\n+c")
+	expectResponse(t, client, "LRANGE queue -2 10", "*2\n+b\n+c")
+	expectResponse(t, client, "LRANGE queue 5 10", "*0")
+	expectResponse(t, client, "TYPE queue", "+list")
+
+	if n, err := client.LPush("queue", "y", "z"); err != nil || n != 5 {
+		t.Errorf("Expected length 5, got %d (err %v)", n, err)
+	}
+	if elements, _ := client.LRange("queue", 0, -1); fmt.Sprint(elements) != "[z y a b c]" {
+		t.Errorf("Expected [z y a b c], got %v", elements)
+	}
+	if value, err := client.RPop("queue"); err != nil || value != "c" {
+		t.Errorf("Expected 'c', got %q (err %v)", value, err)
+	}
+	if err := client.LTrim("queue", 1, -2); err != nil {
+		t.Fatalf("LTrim failed: %v", err)
+	}
+	if elements, _ := client.LRange("queue", 0, -1); fmt.Sprint(elements) != "[y a]" {
+		t.Errorf("Expected [y a], got %v", elements)
+	}
+
+	// popping the last element deletes the key
+	client.LPop("queue")
+	if value, _ := client.LPop("queue"); value != "a" {
+		t.Errorf("Expected 'a', got %q", value)
+	}
+	if _, err := client.LPop("queue"); !errors.Is(err, cache.ErrNotFound) {
+		t.Errorf("Expected ErrNotFound, got %v", err)
+	}
+	expectResponse(t, client, "LLEN queue", ":0")
+	expectResponse(t, client, "TYPE queue", "+none")
+
+	client.Set("name", "gcache")
+	if _, err := client.RPush("name", "x"); !errors.Is(err, cache.ErrWrongType) {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+	expectResponse(t, client, "BLPOP name 1", "-WRONGTYPE Operation against a key holding the wrong kind of value")
+	expectResponse(t, client, "LTRIM queue a 1", "-ERR value is not an integer")
+	expectResponse(t, client, "BLPOP queue -1", "-ERR timeout is negative")
+}
+
+func TestServerBlockingPop(t *testing.T) {
+	server, addr := startServer(t, cache.ServerConfig{Capacity: 10})
+	client := connect(t, addr)
+
+	client.RPush("jobs", "ready")
+	if key, value, ok, err := client.BLPop(time.Second, "empty", "jobs"); err != nil || !ok || key != "jobs" || value != "ready" {
+		t.Errorf("Expected jobs/ready without blocking, got %s/%s %v (err %v)", key, value, ok, err)
+	}
+
+	start := time.Now()
+	if _, _, ok, err := client.BRPop(50*time.Millisecond, "jobs"); err != nil || ok {
+		t.Errorf("Expected timeout, got %v (err %v)", ok, err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("Expected BRPOP to block for the timeout, returned after %v", elapsed)
+	}
+
+	// blocked clients are served in the order they blocked
+	type result struct {
+		worker int
+		value  string
+	}
+	results := make(chan result, 3)
+	for worker := 0; worker < 3; worker++ {
+		c := connect(t, addr)
+		go func() {
+			_, value, _, err := c.BLPop(0, "jobs")
+			if err != nil {
+				value = err.Error()
+			}
+			results <- result{worker, value}
+		}()
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	client.RPush("jobs", "first", "second")
+	got := make(map[int]string)
+	for range 2 {
+		select {
+		case r := <-results:
+			got[r.worker] = r.value
+		case <-time.After(time.Second):
+			t.Fatal("Blocked client was not woken by a push")
+		}
+	}
+	if got[0] != "first" || got[1] != "second" {
+		t.Errorf("Expected workers 0 and 1 to get first and second, got %v", got)
+	}
+	if n, _ := client.LLen("jobs"); n != 0 {
+		t.Errorf("Expected pushed elements handed to blocked clients, %d left", n)
+	}
+
+	// the last one is released when the server stops
+	server.Stop()
+	select {
+	case r := <-results:
+		if !strings.Contains(r.value, "shutting down") {
+			t.Errorf("Expected shutdown error, got %q", r.value)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Blocked client was not released by Stop")
+	}
+}
+
+func TestServerSet(t *testing.T) {
+	client := startTestServer(t, cache.ServerConfig{Capacity: 10})
+
+	if n, err := client.SAdd("seen:a", "ann", "bob", "cid", "bob"); err != nil || n != 3 {
+		t.Fatalf("Expected 3 new members, got %d (err %v)", n, err)
+	}
+	expectResponse(t, client, "SADD seen:a bob dan", ":1")
+	expectResponse(t, client, "SADD seen:b cid dan eve", ":3")
+	expectResponse(t, client, "SMEMBERS seen:a", "*4\n+ann\n+bob\n+cid\n+dan")
+	expectResponse(t, client, "SISMEMBER seen:a ann", ":1")
+	expectResponse(t, client, "SISMEMBER seen:a eve", ":0")
+	expectResponse(t, client, "SCARD seen:a", ":4")
+	expectResponse(t, client, "TYPE seen:a", "+set")
+
+	if members, err := client.SInter("seen:a", "seen:b"); err != nil || fmt.Sprint(members) != "[cid dan]" {
+		t.Errorf("Expected [cid dan], got %v (err %v)", members, err)
+	}
+	if members, _ := client.SUnion("seen:a", "seen:b"); fmt.Sprint(members) != "[ann bob cid dan eve]" {
+		t.Errorf("Expected all five members, got %v", members)
+	}
+	if members, _ := client.SDiff("seen:a", "seen:b", "missing"); fmt.Sprint(members) != "[ann bob]" {
+		t.Errorf("Expected [ann bob], got %v", members)
+	}
+	expectResponse(t, client, "SINTER seen:a missing", "*0")
+
+	if n, err := client.SInterStore("both", "seen:a", "seen:b"); err != nil || n != 2 {
+		t.Errorf("Expected 2 stored members, got %d (err %v)", n, err)
+	}
+	expectResponse(t, client, "SMEMBERS both", "*2\n+cid\n+dan")
+	expectResponse(t, client, "SUNIONSTORE both seen:b", ":3")
+	expectResponse(t, client, "SDIFFSTORE both seen:b seen:b", ":0")
+	expectResponse(t, client, "TYPE both", "+none")
+
+	if members, _ := client.SRandMember("seen:a", 10); len(members) != 4 {
+		t.Errorf("Expected all 4 members, got %v", members)
+	}
+	if members, _ := client.SRandMember("seen:a", -6); len(members) != 6 {
+		t.Errorf("Expected 6 members with repeats, got %v", members)
+	}
+	expectResponse(t, client, "SRANDMEMBER seen:a -1000000000", "-ERR value is out of range, a negative count must be at least -65536")
+
+	popped, err := client.SPop("seen:a", 3)
+	if err != nil || len(popped) != 3 {
+		t.Fatalf("Expected 3 popped members, got %v (err %v)", popped, err)
+	}
+	if n, _ := client.SRem("seen:a", popped...); n != 0 {
+		t.Errorf("Expected popped members to be gone, removed %d", n)
+	}
+	expectResponse(t, client, "SCARD seen:a", ":1")
+	client.SPop("seen:a", 1)
+	expectResponse(t, client, "TYPE seen:a", "+none")
+	expectResponse(t, client, "SPOP seen:a", "-ERR key not found")
+
+	client.Set("name", "gcache")
+	if _, err := client.SUnion("seen:b", "name"); !errors.Is(err, cache.ErrWrongType) {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+	expectResponse(t, client, "SPOP seen:b -1", "-ERR value is out of range, must be positive")
+	expectResponse(t, client, "SINTERSTORE dest", "-ERR wrong number of arguments for 'SINTERSTORE' command")
+}
+
+func TestServerSortedSet(t *testing.T) {
+	client := startTestServer(t, cache.ServerConfig{Capacity: 10})
+
+	if n, err := client.ZAdd("board", map[string]float64{"ann": 30, "bob": 10, "cid": 20}); err != nil || n != 3 {
+		t.Fatalf("Expected 3 new members, got %d (err %v)", n, err)
+	}
+	expectResponse(t, client, "ZADD board 25 dan 15 bob", ":1")
+	expectResponse(t, client, "ZRANGE board 0 -1", "*4\n+bob\n+cid\n+dan\n+ann")
+	expectResponse(t, client, "ZREVRANGE board 0 1 WITHSCORES", "*4\n+ann\n+30\n+dan\n+25")
+	expectResponse(t, client, "ZRANK board dan", ":2")
+	expectResponse(t, client, "ZSCORE board bob", "+15")
+	expectResponse(t, client, "ZSCORE board eve", "-ERR member not found")
+	expectResponse(t, client, "ZCARD board", ":4")
+	expectResponse(t, client, "TYPE board", "+zset")
+
+	if score, err := client.ZIncrBy("board", "bob", 20.5); err != nil || score != 35.5 {
+		t.Errorf("Expected 35.5, got %f (err %v)", score, err)
+	}
+	if rank, _ := client.ZRank("board", "bob"); rank != 3 {
+		t.Errorf("Expected bob to move to rank 3, got %d", rank)
+	}
+	top, err := client.ZRevRangeWithScores("board", 0, 0)
+	if err != nil || len(top) != 1 || top[0] != (cache.ScoredMember{Member: "bob", Score: 35.5}) {
+		t.Errorf("Expected bob on top, got %v (err %v)", top, err)
+	}
+
+	if members, _ := client.ZRangeByScore("board", "20", "(30"); fmt.Sprint(members) != "[{cid 20} {dan 25}]" {
+		t.Errorf("Expected cid and dan, got %v", members)
+	}
+	expectResponse(t, client, "ZRANGEBYSCORE board -inf +inf LIMIT 1 2", "*2\n+dan\n+ann")
+	expectResponse(t, client, "ZRANGEBYSCORE board (35.5 +inf", "*0")
+
+	if n, _ := client.ZRem("board", "ann", "eve"); n != 1 {
+		t.Errorf("Expected 1 removed member, got %d", n)
+	}
+	client.ZRem("board", "bob", "cid", "dan")
+	expectResponse(t, client, "TYPE board", "+none")
+
+	client.Set("name", "gcache")
+	if _, err := client.ZScore("name", "x"); !errors.Is(err, cache.ErrWrongType) {
+		t.Errorf("Expected ErrWrongType, got %v", err)
+	}
+	expectResponse(t, client, "ZADD board high ann", "-ERR value is not a valid float")
+	expectResponse(t, client, "ZADD board inf ann", ":1")
+	expectResponse(t, client, "ZINCRBY board -inf ann", "-ERR resulting score is not a number (NaN)")
+	expectResponse(t, client, "ZRANGEBYSCORE board low 1", "-ERR min or max is not a float")
+}
+
+func TestServerNamespaces(t *testing.T) {
+	_, addr := startServer(t, cache.ServerConfig{
+		Capacity:      10,
+		Namespaces:    map[string]cache.NamespaceConfig{"small": {Capacity: 2}},
+		MaxNamespaces: 3,
+	})
+	team, other := connect(t, addr), connect(t, addr)
+
+	if err := team.Select("small"); err != nil {
+		t.Fatalf("Select failed: %v", err)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		team.Set(key, "team")
+	}
+	other.Set("a", "other")
+
+	// same key, separate namespaces, each with its own capacity
+	if value, _ := team.Get("a"); value == "team" {
+		t.Error("Expected 'a' to be evicted from the small namespace")
+	}
+	if value, _ := team.Get("c"); value != "team" {
+		t.Errorf("Expected 'team', got %q", value)
+	}
+	if value, _ := other.Get("a"); value != "other" {
+		t.Errorf("Expected 'other', got %q", value)
+	}
+	expectResponse(t, team, "CONFIG GET capacity", ":2")
+	expectResponse(t, other, "CONFIG GET capacity", ":10")
+
+	// SIZE, STATS and CLEAR only see the selected namespace
+	if size, _ := team.Size(); size != 2 {
+		t.Errorf("Expected 2 keys in small, got %d", size)
+	}
+	if stats, _ := other.Stats(); stats.Size != 1 || stats.Sets != 1 {
+		t.Errorf("Expected stats of the default namespace only, got %+v", stats)
+	}
+	if err := team.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if size, _ := other.Size(); size != 1 {
+		t.Errorf("Expected CLEAR to keep other namespaces, got size %d", size)
+	}
+
+	team.Set("x", "1")
+	info, err := other.Info()
+	if err != nil {
+		t.Fatalf("Info failed: %v", err)
+	}
+	if !strings.Contains(info, "namespace:0 ") || !strings.Contains(info, "ns_0:keys=1") || !strings.Contains(info, "ns_small:keys=1") {
+		t.Errorf("Expected per-namespace key counts, got %q", info)
+	}
+
+	expectResponse(t, team, "SELECT extra", "+OK")
+	expectResponse(t, team, "SELECT another", "-ERR too many namespaces")
+
+	// pushes only wake clients blocked in the same namespace
+	done := make(chan bool, 1)
+	go func() {
+		_, _, ok, _ := team.BLPop(200*time.Millisecond, "jobs")
+		done <- ok
+	}()
+	time.Sleep(20 * time.Millisecond)
+	other.RPush("jobs", "job")
+	if <-done {
+		t.Error("Expected BLPOP in another namespace to time out")
+	}
+
+	if err := other.FlushAll(); err != nil {
+		t.Fatalf("FlushAll failed: %v", err)
+	}
+	team.Select("small")
+	for _, client := range []*cache.Client{team, other} {
+		if size, _ := client.Size(); size != 0 {
+			t.Errorf("Expected FLUSHALL to clear every namespace, got size %d", size)
+		}
+	}
+}
+
+func TestServerTags(t *testing.T) {
+	client := startTestServer(t, cache.ServerConfig{Capacity: 10})
+
+	if err := client.SetTagged("page:/shoes", "<html>", "product:7", "category:shoes"); err != nil {
+		t.Fatalf("SetTagged failed: %v", err)
+	}
+	expectResponse(t, client, "SETTAGGED page:/boots boots page EX 100 TAGS product:7", "+OK")
+	expectResponse(t, client, "SETTAGGED page:/hats hats page TAGS product:8", "+OK")
+	expectResponse(t, client, "GET page:/boots", "+boots page")
+	expectResponse(t, client, "TTL page:/boots", ":100")
+
+	if n, err := client.Invalidate("product:7"); err != nil || n != 2 {
+		t.Errorf("Expected 2 invalidated keys, got %d (err %v)", n, err)
+	}
+	if _, err := client.Get("page:/shoes"); err == nil {
+		t.Error("Expected 'page:/shoes' to be invalidated")
+	}
+	expectResponse(t, client, "GET page:/hats", "+hats page")
+	expectResponse(t, client, "INVALIDATE product:7", ":0")
+
+	expectResponse(t, client, "SETTAGGED key value", "-ERR wrong number of arguments for 'SETTAGGED' command")
+	expectResponse(t, client, "SETTAGGED key value TAGS", "-ERR wrong number of arguments for 'SETTAGGED' command")
+}
+
+func TestServerPin(t *testing.T) {
+	client := startTestServer(t, cache.ServerConfig{Capacity: 4})
+
+	if err := client.SetPinned("flag:a", "on"); err != nil {
+		t.Fatalf("SetPinned failed: %v", err)
+	}
+	expectResponse(t, client, "SET flag:b off EX 100 PIN", "+OK")
+	expectResponse(t, client, "TTL flag:b", ":100")
+
+	// half of the capacity is pinned, the limit
+	expectResponse(t, client, "SET greeting hello world PIN EX 100", "-ERR pinned entries would exceed their share of the cache")
+	expectResponse(t, client, "SET greeting hello world EX 100", "+OK")
+	expectResponse(t, client, "PIN greeting", "-ERR pinned entries would exceed their share of the cache")
+	if err := client.SetPinned("flag:c", "on"); err != cache.ErrPinLimit {
+		t.Errorf("Expected ErrPinLimit, got %v", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		client.Set(fmt.Sprintf("key%d", i), "v")
+	}
+	expectResponse(t, client, "GET flag:a", "+on")
+	expectResponse(t, client, "GET flag:b", "+off")
+
+	stats, err := client.Stats()
+	if err != nil || stats.Pinned != 2 || stats.PinnedShare != 0.5 {
+		t.Errorf("Expected 2 pinned keys using 0.5, got %d using %v (err %v)", stats.Pinned, stats.PinnedShare, err)
+	}
+
+	if unpinned, err := client.Unpin("flag:a"); err != nil || !unpinned {
+		t.Errorf("Expected flag:a to be unpinned, got %t (err %v)", unpinned, err)
+	}
+	expectResponse(t, client, "UNPIN flag:a", ":0")
+	expectResponse(t, client, "PIN missing", ":0")
+	if pinned, err := client.Pin("key9"); err != nil || !pinned {
+		t.Errorf("Expected key9 to be pinned, got %t (err %v)", pinned, err)
+	}
+	expectResponse(t, client, "PIN", "-ERR wrong number of arguments for 'PIN' command")
+
+	// PIN is only an option in upper case, other words stay in the value
+	expectResponse(t, client, "UNPIN key9", ":1")
+	expectResponse(t, client, "SET hint enter your pin", "+OK")
+	expectResponse(t, client, "GET hint", "+enter your pin")
+	expectResponse(t, client, "UNPIN hint", ":0")
+	expectResponse(t, client, "SET code PIN", "+OK")
+	expectResponse(t, client, "GET code", "+PIN")
+	expectResponse(t, client, "UNPIN code", ":0")
+	expectResponse(t, client, "SETTAGGED k v PIN TAGS t", "-ERR PIN is not supported by 'SETTAGGED'")
+}
+
+func TestClientSetValueRoundTrip(t *testing.T) {
+	client := startTestServer(t, cache.ServerConfig{Capacity: 10})
+
+	// values the server would read partly as options are refused
+	for _, value := range []string{"my PIN", "wait EX 10", "ping PX 5 PIN"} {
+		if err := client.Set("k", value); !errors.Is(err, cache.ErrAmbiguousValue) {
+			t.Errorf("Set(%q): expected ErrAmbiguousValue, got %v", value, err)
+		}
+		if err := client.SetWithTTL("k", value, time.Minute); !errors.Is(err, cache.ErrAmbiguousValue) {
+			t.Errorf("SetWithTTL(%q): expected ErrAmbiguousValue, got %v", value, err)
+		}
+		if err := client.SetPinned("k", value); !errors.Is(err, cache.ErrAmbiguousValue) {
+			t.Errorf("SetPinned(%q): expected ErrAmbiguousValue, got %v", value, err)
+		}
+	}
+	if err := client.SetTagged("k", "price TAGS sale", "t"); !errors.Is(err, cache.ErrAmbiguousValue) {
+		t.Errorf("SetTagged: expected ErrAmbiguousValue, got %v", err)
+	}
+	if _, err := client.Get("k"); err == nil {
+		t.Error("Expected no refused value to be stored")
+	}
+
+	// others are stored as they are
+	for _, value := range []string{"my pin", "PIN", "EX 10", "EX 10 later", "deus ex machina"} {
+		if err := client.SetWithTTL("k", value, time.Minute); err != nil {
+			t.Fatalf("SetWithTTL(%q) failed: %v", value, err)
+		}
+		if got, err := client.Get("k"); err != nil || got != value {
+			t.Errorf("Expected %q back, got %q (err %v)", value, got, err)
+		}
+	}
+	if pinned, _ := client.Unpin("k"); pinned {
+		t.Error("Expected k not to be pinned")
+	}
+}
+
+func TestServerObject(t *testing.T) {
+	client := startTestServer(t, cache.ServerConfig{Capacity: 10})
+
+	client.Set("a", "1")
+	client.Set("b", "hello world")
+	client.Set("c", "3")
+	client.Get("a")
+	client.Get("a")
+
+	if freq, err := client.Freq("a"); err != nil || freq != 3 {
+		t.Errorf("Expected 3 accesses of a, got %d (err %v)", freq, err)
+	}
+	if idle, err := client.IdleTime("b"); err != nil || idle != 0 {
+		t.Errorf("Expected b to be idle for 0s, got %v (err %v)", idle, err)
+	}
+
+	a, err := client.MemoryUsage("a")
+	if err != nil {
+		t.Fatalf("MemoryUsage failed: %v", err)
+	}
+	if b, _ := client.MemoryUsage("b"); b != a+10 {
+		t.Errorf("Expected b to use 10 bytes more than a (%d), got %d", a, b)
+	}
+
+	if keys, err := client.Coldest(2); err != nil || fmt.Sprint(keys) != "[b c]" {
+		t.Errorf("Expected coldest [b c], got %v (err %v)", keys, err)
+	}
+	expectResponse(t, client, "DEBUG LRU 0", "*0")
+	expectResponse(t, client, "OBJECT FREQ missing", "-ERR key not found")
+	expectResponse(t, client, "MEMORY USAGE missing", "-ERR key not found")
+	expectResponse(t, client, "OBJECT ENCODING a", "-ERR unknown OBJECT subcommand 'ENCODING'")
+	expectResponse(t, client, "DEBUG LRU x", "-ERR value is out of range, must be positive")
+	expectResponse(t, client, "MEMORY", "-ERR wrong number of arguments for 'MEMORY' command")
+}
+
+func TestServerScan(t *testing.T) {
+	client := startTestServer(t, cache.ServerConfig{Capacity: 1000})
+
+	for _, key := range []string{"user:1", "user:2", "user:10", "user:a", "order:1", "star*key", "[x]"} {
+		client.Set(key, "v")
+	}
+
+	for pattern, want := range map[string]string{
+		"user:?":      "[user:1 user:2 user:a]",
+		"user:*":      "[user:1 user:10 user:2 user:a]",
+		"user:[0-9]*": "[user:1 user:10 user:2]",
+		"user:[^0-9]": "[user:a]",
+		"*:1":         "[order:1 user:1]",
+		"star\\*key":  "[star*key]",
+		"\\[x]":       "[[x]]",
+		"nothing*":    "[]",
+	} {
+		if keys, err := client.Keys(pattern); err != nil || fmt.Sprint(keys) != want {
+			t.Errorf("KEYS %s: expected %s, got %v (err %v)", pattern, want, keys, err)
+		}
+	}
+
+	for i := 0; i < 200; i++ {
+		client.Set(fmt.Sprintf("item:%d", i), "v")
+	}
+	seen := make(map[string]bool)
+	for key, err := range client.Scan("item:*", 15) {
+		if err != nil {
+			t.Fatalf("Scan failed: %v", err)
+		}
+		if seen[key] {
+			t.Errorf("Key %s returned twice", key)
+		}
+		seen[key] = true
+	}
+	if len(seen) != 200 {
+		t.Errorf("Expected 200 keys, got %d", len(seen))
+	}
+
+	expectResponse(t, client, "SCAN abc", "-ERR invalid cursor")
+	expectResponse(t, client, "SCAN 0 COUNT", "-ERR syntax error")
+	expectResponse(t, client, "SCAN 0 COUNT 0", "-ERR value is not an integer or out of range")
+}